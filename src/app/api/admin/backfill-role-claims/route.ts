@@ -0,0 +1,139 @@
+import { env } from '@/env';
+import { initFirebaseAdmin } from '@/firebase/admin';
+import { ROLES } from '@/types/enum';
+import type { UserRecord } from 'firebase-admin/auth';
+
+import { NextRequest, NextResponse } from 'next/server';
+
+// Sets the role custom claim of every member's login account from their role
+// in xoxo/members. Accounts created before api/members/create set the claim
+// do not carry one, and the role-based rules generated from
+// firebase.rule.spec.json deny them everything: run this, with dryRun first,
+// and check that withoutRole is empty before deploying those rules.
+//
+// The caller sends an admin's Firebase ID token as a Bearer token. Admins
+// whose account predates the claim are recognised by their member role.
+export async function POST(request: NextRequest) {
+  try {
+    const idToken = request.headers.get('authorization')?.match(/^Bearer (.+)$/)?.[1];
+    if (!idToken) {
+      return NextResponse.json(
+        { error: 'An admin ID token is required' },
+        { status: 401 }
+      );
+    }
+
+    const { dryRun = false } = await request.json().catch(() => ({}));
+
+    const admin = await initFirebaseAdmin();
+    if (!admin) {
+      return NextResponse.json(
+        { error: 'Failed to initialize Firebase Admin' },
+        { status: 500 }
+      );
+    }
+
+    const db = admin.app().database(env.NEXT_PUBLIC_FIREBASE_DATABASE_URL);
+    const snapshot = await db.ref('xoxo/members').once('value');
+    const members: Record<string, any> = snapshot.val() || {};
+
+    let caller;
+    try {
+      caller = await admin.auth().verifyIdToken(idToken);
+    } catch {
+      return NextResponse.json(
+        { error: 'Invalid ID token' },
+        { status: 401 }
+      );
+    }
+    const callerIsAdmin = caller.role === ROLES.admin ||
+      Object.values(members).some(member =>
+        member.role === ROLES.admin &&
+        (member.loginAccount === caller.uid || (!!caller.email && member.email === caller.email))
+      );
+    if (!callerIsAdmin) {
+      return NextResponse.json(
+        { error: 'Only admins can backfill role claims' },
+        { status: 403 }
+      );
+    }
+
+    const validRoles = Object.values(ROLES) as string[];
+    const updated: string[] = [];
+    const unchanged: string[] = [];
+    const noAccount: string[] = [];
+    const invalidRole: string[] = [];
+    const claimed = new Set<string>();
+
+    for (const [id, member] of Object.entries(members)) {
+      if (!validRoles.includes(member.role)) {
+        invalidRole.push(id);
+        continue;
+      }
+
+      // Tìm tài khoản theo loginAccount (UID), sau đó theo email
+      let user: UserRecord | undefined;
+      try {
+        if (member.loginAccount) {
+          user = await admin.auth().getUser(member.loginAccount);
+        }
+      } catch (error: any) {
+        if (error.code !== 'auth/user-not-found') throw error;
+      }
+      if (!user && member.email) {
+        try {
+          user = await admin.auth().getUserByEmail(member.email);
+        } catch (error: any) {
+          if (error.code !== 'auth/user-not-found') throw error;
+        }
+      }
+      if (!user) {
+        noAccount.push(id);
+        continue;
+      }
+
+      claimed.add(user.uid);
+      if (user.customClaims?.role === member.role) {
+        unchanged.push(id);
+        continue;
+      }
+      if (!dryRun) {
+        await admin.auth().setCustomUserClaims(user.uid, {
+          ...user.customClaims,
+          role: member.role,
+        });
+      }
+      updated.push(id);
+    }
+
+    // Tài khoản vẫn không có role sau khi cập nhật sẽ bị rules mới chặn
+    const withoutRole: string[] = [];
+    let pageToken: string | undefined;
+    do {
+      const page = await admin.auth().listUsers(1000, pageToken);
+      for (const user of page.users) {
+        if (!claimed.has(user.uid) && !validRoles.includes(user.customClaims?.role)) {
+          withoutRole.push(user.uid);
+        }
+      }
+      pageToken = page.pageToken;
+    } while (pageToken);
+
+    return NextResponse.json({
+      success: true,
+      dryRun,
+      updated,
+      unchanged,
+      noAccount,
+      invalidRole,
+      withoutRole,
+    });
+
+  } catch (error: any) {
+    console.error('Error backfilling role claims:', error);
+    return NextResponse.json(
+      { error: 'Failed to backfill role claims', details: error.message, code: error.code },
+      { status: 500 }
+    );
+  }
+}
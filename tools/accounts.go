@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultMemberPassword = "123456"
+
+// AccountOptions configures the Auth emulator accounts created for members.
+type AccountOptions struct {
+	Enabled      bool
+	EmulatorHost string
+	Password     string
+	// RolePasswords overrides Password for members of a role.
+	RolePasswords map[string]string
+}
+
+func (o *AccountOptions) register(flags *flag.FlagSet) {
+	emulatorHost := os.Getenv("FIREBASE_AUTH_EMULATOR_HOST")
+	if emulatorHost == "" {
+		emulatorHost = defaultAuthEmulatorHost
+	}
+	o.RolePasswords = make(map[string]string)
+	flags.BoolVar(&o.Enabled, "accounts", true, "create an Auth emulator account for every member, replacing the emulator's existing accounts")
+	flags.StringVar(&o.EmulatorHost, "auth-emulator-host", emulatorHost, "Auth emulator host ($FIREBASE_AUTH_EMULATOR_HOST)")
+	flags.StringVar(&o.Password, "password", defaultMemberPassword, "password for every member account")
+	flags.Func("role-password", "password for one role, as role=password; repeatable", func(value string) error {
+		role, password, ok := strings.Cut(value, "=")
+		if !ok || role == "" {
+			return fmt.Errorf("want role=password, got %q", value)
+		}
+		o.RolePasswords[role] = password
+		return nil
+	})
+}
+
+func (o AccountOptions) passwordFor(role string) string {
+	if password, ok := o.RolePasswords[role]; ok {
+		return password
+	}
+	return o.Password
+}
+
+// authEmulator calls the Identity Toolkit API served by the Auth emulator, as
+// the Admin SDK does for createUser and setCustomUserClaims.
+type authEmulator struct {
+	baseURL string
+	project string
+	retries int
+	http    *http.Client
+}
+
+func (a *authEmulator) call(method, path string, request, response any) error {
+	var body []byte
+	if request != nil {
+		var err error
+		if body, err = json.Marshal(request); err != nil {
+			return err
+		}
+	}
+	respBody, err := doWithRetries(a.http, a.retries, func() (*http.Request, error) {
+		req, err := http.NewRequest(method, a.baseURL+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer owner")
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	if response != nil {
+		return json.Unmarshal(respBody, response)
+	}
+	return nil
+}
+
+// createLoginAccounts replaces the Auth emulator's accounts with one per
+// member, signing in with the member's email and carrying their role as a
+// custom claim like api/members/create. The member's loginAccount is used as
+// the UID and updated with the UID the emulator returns. Members no longer
+// active get disabled accounts.
+func createLoginAccounts(members map[string]Member, project string, options AccountOptions, retries int) (int, error) {
+	for role, password := range options.RolePasswords {
+		if len(password) < 6 {
+			return 0, fmt.Errorf("password for role %s must be at least 6 characters", role)
+		}
+	}
+	if len(options.Password) < 6 {
+		return 0, fmt.Errorf("password must be at least 6 characters")
+	}
+
+	auth := &authEmulator{
+		baseURL: "http://" + strings.TrimSuffix(options.EmulatorHost, "/"),
+		project: project,
+		retries: retries,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+	if err := auth.call(http.MethodDelete, "/emulator/v1/projects/"+project+"/accounts", nil, nil); err != nil {
+		return 0, err
+	}
+
+	ids := make([]string, 0, len(members))
+	for id := range members {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	accountsPath := "/identitytoolkit.googleapis.com/v1/projects/" + project + "/accounts"
+	for _, id := range ids {
+		member := members[id]
+		var created struct {
+			LocalID string `json:"localId"`
+		}
+		err := auth.call(http.MethodPost, accountsPath, map[string]any{
+			"localId":       member.LoginAccount,
+			"email":         member.Email,
+			"password":      options.passwordFor(member.Role),
+			"displayName":   member.Name,
+			"emailVerified": true,
+			"disabled":      !member.IsActive,
+		}, &created)
+		if err != nil {
+			return 0, fmt.Errorf("member %s (%s): %w", id, member.Email, err)
+		}
+
+		claims, _ := json.Marshal(map[string]string{"role": member.Role})
+		err = auth.call(http.MethodPost, accountsPath+":update", map[string]any{
+			"localId":          created.LocalID,
+			"customAttributes": string(claims),
+		}, nil)
+		if err != nil {
+			return 0, fmt.Errorf("set role of member %s: %w", id, err)
+		}
+
+		member.LoginAccount = created.LocalID
+		members[id] = member
+	}
+	return len(ids), nil
+}
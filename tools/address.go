@@ -0,0 +1,161 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+//go:embed data/admin_units.json
+var adminUnitsJSON []byte
+
+// addresses is the address source of the generator, built in main from the
+// embedded units and the vocabulary's addressWeights.
+var addresses *AddressBook
+
+// AdminUnits is the embedded province/district/ward dataset. Codes are the
+// numeric codes of provinces.open-api.vn, which is what CustomerFormModal
+// stores in the province, district and ward fields.
+//
+// It is not the country's full list: it holds Hồ Chí Minh City, where the
+// workshop is, and Hà Nội and Đà Nẵng, the cities most customers who send
+// their items in live in, with some of their inner districts and wards, so
+// every address is in one of them. Customers from other provinces need those
+// provinces added to data/admin_units.json first.
+type AdminUnits struct {
+	Source    string          `json:"source,omitempty"`
+	Provinces []AdminProvince `json:"provinces"`
+}
+
+type AdminProvince struct {
+	Code      int             `json:"code"`
+	Name      string          `json:"name"`
+	Streets   []string        `json:"streets"`
+	Districts []AdminDistrict `json:"districts"`
+}
+
+type AdminDistrict struct {
+	Code  int         `json:"code"`
+	Name  string      `json:"name"`
+	Wards []AdminWard `json:"wards"`
+}
+
+type AdminWard struct {
+	Code int    `json:"code"`
+	Name string `json:"name"`
+}
+
+// Address is a generated street address with its structured parts.
+type Address struct {
+	HouseNumber  string
+	Street       string
+	ProvinceCode int
+	ProvinceName string
+	DistrictCode int
+	DistrictName string
+	WardCode     int
+	WardName     string
+}
+
+// Full renders the address the way it is typed into order and customer forms.
+func (a Address) Full() string {
+	return fmt.Sprintf("%s %s, %s, %s, %s", a.HouseNumber, a.Street, a.WardName, a.DistrictName, a.ProvinceName)
+}
+
+// IDCardPrefix is the 3-digit province code that starts a CCCD number.
+func (a Address) IDCardPrefix() string {
+	return fmt.Sprintf("%03d", a.ProvinceCode)
+}
+
+func loadAdminUnits() (*AdminUnits, error) {
+	var units AdminUnits
+	if err := json.Unmarshal(adminUnitsJSON, &units); err != nil {
+		return nil, fmt.Errorf("parse administrative units: %w", err)
+	}
+	for _, province := range units.Provinces {
+		if len(province.Streets) == 0 || len(province.Districts) == 0 {
+			return nil, fmt.Errorf("province %d (%s) needs streets and districts", province.Code, province.Name)
+		}
+		for _, district := range province.Districts {
+			if len(district.Wards) == 0 {
+				return nil, fmt.Errorf("district %d (%s) has no wards", district.Code, district.Name)
+			}
+		}
+	}
+	return &units, nil
+}
+
+// AddressBook draws addresses from the administrative units, picking the
+// province by weight, i.e. sharing the customers out between the workshop's
+// city and the others.
+type AddressBook struct {
+	provinces   []AdminProvince
+	cumulative  []int
+	totalWeight int
+}
+
+// newAddressBook weights provinces by province code. Provinces of the
+// dataset missing from weights get weight 1; a weight of 0 leaves the
+// province out.
+func newAddressBook(units *AdminUnits, weights map[string]int) (*AddressBook, error) {
+	known := make(map[string]bool)
+	for _, province := range units.Provinces {
+		known[fmt.Sprint(province.Code)] = true
+	}
+	codes := make([]string, 0, len(weights))
+	for code := range weights {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if !known[code] {
+			return nil, fmt.Errorf("addressWeights references unknown province code %q", code)
+		}
+		if weights[code] < 0 {
+			return nil, fmt.Errorf("addressWeights for province %q must not be negative", code)
+		}
+	}
+
+	book := &AddressBook{}
+	for _, province := range units.Provinces {
+		weight, ok := weights[fmt.Sprint(province.Code)]
+		if !ok {
+			weight = 1
+		}
+		if weight == 0 {
+			continue
+		}
+		book.totalWeight += weight
+		book.provinces = append(book.provinces, province)
+		book.cumulative = append(book.cumulative, book.totalWeight)
+	}
+	if book.totalWeight == 0 {
+		return nil, fmt.Errorf("addressWeights leaves no province to draw addresses from")
+	}
+	return book, nil
+}
+
+func (b *AddressBook) Random() Address {
+	pick := rand.Intn(b.totalWeight)
+	province := b.provinces[sort.SearchInts(b.cumulative, pick+1)]
+	district := province.Districts[rand.Intn(len(province.Districts))]
+	ward := district.Wards[rand.Intn(len(district.Wards))]
+
+	houseNumber := fmt.Sprint(1 + rand.Intn(300))
+	if rand.Float32() < 0.2 {
+		houseNumber = fmt.Sprintf("%s/%d", houseNumber, 1+rand.Intn(40))
+	}
+
+	return Address{
+		HouseNumber:  houseNumber,
+		Street:       province.Streets[rand.Intn(len(province.Streets))],
+		ProvinceCode: province.Code,
+		ProvinceName: province.Name,
+		DistrictCode: district.Code,
+		DistrictName: district.Name,
+		WardCode:     ward.Code,
+		WardName:     ward.Name,
+	}
+}
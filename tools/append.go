@@ -0,0 +1,355 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// appendSources are the collections read from the existing dataset: who can
+// take and work on orders, which customers and codes already exist.
+var appendSources = []string{"departments", "members", "workflows", "customers", "orders", "finance/transactions"}
+
+// AppendOptions configures the append command: how many orders to add and
+// the database they go to when no file is given.
+type AppendOptions struct {
+	DatabaseOptions
+	Orders int
+	// ReturningRate is the share of new orders placed by existing customers.
+	ReturningRate float64
+	// DryRun lists the paths that would be written without writing them.
+	DryRun bool
+	Images ImageOptions
+}
+
+func (o *AppendOptions) register(flags *flag.FlagSet) {
+	o.DatabaseOptions.register(flags)
+	flags.IntVar(&o.Orders, "orders", 10, "number of orders to add")
+	flags.Float64Var(&o.ReturningRate, "returning-customers", 0.3, "share of new orders (0-1) placed by customers already in the dataset")
+	flags.BoolVar(&o.DryRun, "dry-run", false, "print the paths the update would write instead of writing them")
+	o.Images.register(flags)
+}
+
+// readAppendSources reads appendSources from a dataset file, returning the
+// whole file's tree as well so it can be written back with everything in it.
+func readAppendSources(path, root string) (MockData, any, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return MockData{}, nil, err
+	}
+	var tree any
+	if err := json.Unmarshal(raw, &tree); err != nil {
+		return MockData{}, nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	existing, ok := getAt(tree, splitPath(root)).(map[string]any)
+	if !ok {
+		return MockData{}, nil, fmt.Errorf("%s has no %s tree", path, root)
+	}
+	data, err := decodeAppendSources(existing)
+	return data, tree, err
+}
+
+// fetchAppendSources reads appendSources from the database. Any of them may
+// be missing, as in a database filled by hand.
+func fetchAppendSources(client *RTDBClient, root string) (MockData, error) {
+	reader := &treeReader{client: client, pageSize: 500}
+	existing := make(map[string]any)
+	for _, path := range appendSources {
+		value, err := reader.read(root + "/" + path)
+		if err != nil {
+			return MockData{}, fmt.Errorf("read %s: %w", path, err)
+		}
+		if value != nil {
+			setPath(existing, strings.Split(path, "/"), value)
+		}
+	}
+	return decodeAppendSources(existing)
+}
+
+func decodeAppendSources(existing map[string]any) (MockData, error) {
+	sources := make(map[string]any)
+	for _, path := range appendSources {
+		if value := getAt(existing, splitPath(path)); value != nil {
+			sources = setAt(sources, splitPath(path), value).(map[string]any)
+		}
+	}
+	raw, err := json.Marshal(sources)
+	if err != nil {
+		return MockData{}, err
+	}
+	var data MockData
+	if err := json.Unmarshal(raw, &data.Xoxo); err != nil {
+		return MockData{}, fmt.Errorf("read existing data: %w", err)
+	}
+	return data, nil
+}
+
+// nextIndex returns the number of the last key of the form prefix followed by
+// digits, e.g. 21 for ORD_021 with prefix ORD_, or 0 when there is none.
+func nextIndex[V any](records map[string]V, prefix string) int {
+	last := 0
+	for key := range records {
+		digits, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(digits); err == nil && n > last {
+			last = n
+		}
+	}
+	return last
+}
+
+// appendOrders generates options.Orders orders on top of existing. It returns
+// the records to add: the orders, customers new to the dataset and the income
+// entries of their payments, and the existing customers who placed some of
+// the orders, with their updatedAt and customerGroup brought up to date.
+// Keys and codes continue the existing sequences, skipping any in use.
+func appendOrders(existing MockData, config MockConfig, options AppendOptions, now int64) (MockData, map[string]Customer, error) {
+	xoxo := existing.Xoxo
+	if len(xoxo.Members) == 0 {
+		return MockData{}, nil, fmt.Errorf("the dataset has no members to take the orders")
+	}
+	timeline := newTimeline(defaultTimeModel, time.UnixMilli(now), config.HistoryDays)
+
+	tenures := make(map[string]Tenure, len(xoxo.Members))
+	for id, member := range xoxo.Members {
+		tenures[id] = tenureOf(member)
+	}
+	roster := newRoster(xoxo.Members, tenures)
+
+	deptCodes := make([]string, 0, len(xoxo.Departments))
+	for code := range xoxo.Departments {
+		deptCodes = append(deptCodes, code)
+	}
+	sort.Strings(deptCodes)
+	deptList := make([]VocabDepartment, 0, len(deptCodes))
+	for _, code := range deptCodes {
+		deptList = append(deptList, VocabDepartment{Code: code, Name: xoxo.Departments[code].Name})
+	}
+
+	contacts := newContactBook()
+	for _, member := range xoxo.Members {
+		contacts.Reserve(member.Phone, member.Email)
+	}
+	customerKeys := make([]string, 0, len(xoxo.Customers))
+	for key, customer := range xoxo.Customers {
+		contacts.Reserve(customer.Phone, customer.Email)
+		customerKeys = append(customerKeys, key)
+	}
+	sort.Strings(customerKeys)
+
+	orderCodes := make(map[string]bool, len(xoxo.Orders))
+	customerOrders := make(map[string][]FirebaseOrderData)
+	for _, order := range xoxo.Orders {
+		orderCodes[order.Code] = true
+		customerOrders[order.CustomerCode] = append(customerOrders[order.CustomerCode], order)
+	}
+
+	var added MockData
+	added.Xoxo.Orders = make(map[string]FirebaseOrderData)
+	added.Xoxo.Customers = make(map[string]Customer)
+	added.Xoxo.Finance.Transactions = make(map[string]FinanceTransaction)
+	returning := make(map[string]Customer)
+
+	orderIndex := nextIndex(xoxo.Orders, "ORD_")
+	customerIndex := nextIndex(xoxo.Customers, "CUST_")
+	var income []FinanceTransaction
+	for len(added.Xoxo.Orders) < options.Orders {
+		status := orderStatusScenarios[orderIndex%len(orderStatusScenarios)]
+		schedule := scheduleOrder(timeline, status, now)
+
+		var candidates []string
+		for _, key := range customerKeys {
+			if xoxo.Customers[key].CreatedAt <= schedule.OrderDate {
+				candidates = append(candidates, key)
+			}
+		}
+		var customerKey string
+		var customer Customer
+		if len(candidates) > 0 && rand.Float64() < options.ReturningRate {
+			customerKey = candidates[rand.Intn(len(candidates))]
+			customer = xoxo.Customers[customerKey]
+			if previous, ok := returning[customerKey]; ok {
+				customer = previous
+			}
+			if customer.Code == "" {
+				customer.Code = customerKey
+			}
+		} else {
+			for {
+				customerKey = generateID("CUST", customerIndex)
+				customerIndex++
+				if _, taken := xoxo.Customers[customerKey]; !taken {
+					break
+				}
+			}
+			customer = newCustomer(customerKey, contacts, config.DuplicatePhoneRate, schedule.OrderDate, schedule.OrderDate)
+		}
+
+		orderID, order := generateOrder(orderIndex, status, schedule, customer, deptList, xoxo.Workflows, roster, timeline, now)
+		orderIndex++
+		if _, taken := xoxo.Orders[orderID]; taken || orderCodes[order.Code] {
+			continue
+		}
+		orderCodes[order.Code] = true
+		added.Xoxo.Orders[orderID] = order
+		income = append(income, orderIncome(orderID, order)...)
+
+		customer.UpdatedAt = max(customer.UpdatedAt, order.OrderDate)
+		customerOrders[customer.Code] = append(customerOrders[customer.Code], order)
+		customer.CustomerGroup = customerGroup(customerOrders[customer.Code], false)
+		if _, existed := xoxo.Customers[customerKey]; existed {
+			returning[customerKey] = customer
+		} else {
+			added.Xoxo.Customers[customerKey] = customer
+		}
+	}
+
+	// Income entries keep the ledger's date order among themselves; the
+	// existing entries are not renumbered.
+	sort.SliceStable(income, func(a, b int) bool { return income[a].Date < income[b].Date })
+	financeIndex := nextIndex(xoxo.Finance.Transactions, "FIN_")
+	for _, txn := range income {
+		for {
+			txn.ID = generateFinanceCode(financeIndex)
+			financeIndex++
+			if _, taken := xoxo.Finance.Transactions[txn.ID]; !taken {
+				break
+			}
+		}
+		txn.CreatedAt = txn.Date
+		txn.UpdatedAt = txn.Date
+		added.Xoxo.Finance.Transactions[txn.ID] = txn
+	}
+
+	return added, returning, nil
+}
+
+// appendUpdate is the multi-path update, keyed by paths relative to the root,
+// that writes every added record at its own path and only the changed fields
+// of returning customers, so records edited by hand are left as they are.
+func appendUpdate(added MockData, returning map[string]Customer) map[string]any {
+	update := make(map[string]any)
+	for key, customer := range returning {
+		update["customers/"+key+"/updatedAt"] = customer.UpdatedAt
+		var group any
+		if customer.CustomerGroup != "" {
+			group = customer.CustomerGroup
+		}
+		update["customers/"+key+"/customerGroup"] = group
+	}
+	for id, order := range added.Xoxo.Orders {
+		update["orders/"+id] = order
+	}
+	for code, customer := range added.Xoxo.Customers {
+		update["customers/"+code] = customer
+	}
+	for id, txn := range added.Xoxo.Finance.Transactions {
+		update["finance/transactions/"+id] = txn
+	}
+	return update
+}
+
+// appendCommand runs `append [flags] [mock-data.json]`: new orders on top of
+// the dataset in the file, written back in place, or without a file on top of
+// the database, written as one multi-path update so the records are added
+// together or not at all and nothing else at the root is touched.
+func appendCommand(config MockConfig, options AppendOptions, file string) error {
+	if options.Orders < 1 {
+		return fmt.Errorf("-orders must be at least 1, got %d", options.Orders)
+	}
+	root := strings.Trim(options.Root, "/")
+
+	var (
+		existing MockData
+		tree     any
+		client   *RTDBClient
+		err      error
+		target   string
+	)
+	if file != "" {
+		existing, tree, err = readAppendSources(file, root)
+		target = file
+	} else {
+		if client, err = options.client(); err != nil {
+			return err
+		}
+		existing, err = fetchAppendSources(client, root)
+		target = fmt.Sprintf("%s/%s", client.baseURL, root)
+	}
+	if err != nil {
+		return err
+	}
+
+	added, returning, err := appendOrders(existing, config, options, time.Now().Unix()*1000)
+	if err != nil {
+		return err
+	}
+	if file == "" && options.Images.Enabled && !options.DryRun {
+		urls, err := imageStore.Upload(options.Retries)
+		if err != nil {
+			return fmt.Errorf("upload images: %w", err)
+		}
+		rewriteImageURLs(&added, urls)
+		fmt.Printf("Uploaded %d placeholder images to gs://%s at %s\n", len(urls), imageStore.bucket, imageStore.host)
+	}
+	update := appendUpdate(added, returning)
+
+	paths := make([]string, 0, len(update))
+	for path := range update {
+		paths = append(paths, path)
+	}
+	sortKeys(paths)
+	orderIDs := make([]string, 0, len(added.Xoxo.Orders))
+	for id := range added.Xoxo.Orders {
+		orderIDs = append(orderIDs, id)
+	}
+	sortKeys(orderIDs)
+	summary := fmt.Sprintf("%d orders (%s to %s), %d new customers, %d returning customers and %d finance transactions",
+		len(orderIDs), orderIDs[0], orderIDs[len(orderIDs)-1], len(added.Xoxo.Customers), len(returning), len(added.Xoxo.Finance.Transactions))
+
+	if options.DryRun {
+		fmt.Printf("Would append %s to %s in one update of %d paths:\n", summary, target, len(paths))
+		for _, path := range paths {
+			fmt.Printf("  %s/%s\n", root, path)
+		}
+		return nil
+	}
+
+	body, err := json.Marshal(update)
+	if err != nil {
+		return fmt.Errorf("marshal update: %w", err)
+	}
+	if file == "" {
+		if _, err := client.Do(http.MethodPatch, root, nil, body); err != nil {
+			return fmt.Errorf("write update: %w", err)
+		}
+		fmt.Printf("Appended %s to %s in one update of %d paths (%d KB)\n", summary, target, len(paths), len(body)/1024)
+		return nil
+	}
+
+	var values map[string]any
+	if err := json.Unmarshal(body, &values); err != nil {
+		return err
+	}
+	for _, path := range paths {
+		tree = setAt(tree, append(splitPath(root), splitPath(path)...), values[path])
+	}
+	jsonData, err := json.MarshalIndent(tree, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", file, err)
+	}
+	if err := os.WriteFile(file, jsonData, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", file, err)
+	}
+	fmt.Printf("Appended %s to %s\n", summary, file)
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+// useDefaultVocabulary loads the default pack and the administrative units,
+// as main does before generating anything.
+func useDefaultVocabulary(t *testing.T) {
+	t.Helper()
+	vocab, err := loadVocabulary("default")
+	if err != nil {
+		t.Fatalf("load vocabulary: %v", err)
+	}
+	applyVocabulary(vocab)
+	units, err := loadAdminUnits()
+	if err != nil {
+		t.Fatalf("load administrative units: %v", err)
+	}
+	if addresses, err = newAddressBook(units, vocab.AddressWeights); err != nil {
+		t.Fatalf("build address book: %v", err)
+	}
+}
+
+func TestNextIndex(t *testing.T) {
+	tests := []struct {
+		keys   []string
+		prefix string
+		want   int
+	}{
+		{nil, "ORD_", 0},
+		{[]string{"ORD_001", "ORD_002", "ORD_021"}, "ORD_", 21},
+		{[]string{"ORD_009", "ORD_1000"}, "ORD_", 1000},
+		{[]string{"ORD_003", "ORD_manual", "-Nx8aQ"}, "ORD_", 3},
+		{[]string{"CUST_004", "ORD_007"}, "CUST_", 4},
+		{[]string{"FIN_000120"}, "FIN_", 120},
+	}
+	for _, test := range tests {
+		records := make(map[string]bool, len(test.keys))
+		for _, key := range test.keys {
+			records[key] = true
+		}
+		if got := nextIndex(records, test.prefix); got != test.want {
+			t.Errorf("nextIndex(%v, %q) = %d, want %d", test.keys, test.prefix, got, test.want)
+		}
+	}
+}
+
+func TestAppendOrdersContinuesKeys(t *testing.T) {
+	useDefaultVocabulary(t)
+	config := defaultConfig
+	base := generateMockData(config)
+	now := time.Now().UnixMilli()
+
+	tests := []struct {
+		name string
+		edit func(data *MockData)
+	}{
+		{name: "generated dataset"},
+		{
+			name: "records added by hand",
+			edit: func(data *MockData) {
+				// A key further along the sequence, and keys outside it.
+				last := nextIndex(data.Xoxo.Orders, "ORD_")
+				order := data.Xoxo.Orders[generateID("ORD", 0)]
+				data.Xoxo.Orders[generateID("ORD", last+4)] = order
+				data.Xoxo.Orders["-NmanualOrder"] = order
+				data.Xoxo.Customers["-NmanualCustomer"] = Customer{Name: "Khách nhập tay", CreatedAt: now}
+			},
+		},
+		{
+			name: "no orders yet",
+			edit: func(data *MockData) {
+				data.Xoxo.Orders = nil
+				data.Xoxo.Customers = nil
+				data.Xoxo.Finance.Transactions = nil
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			existing := copyAppendSources(t, base)
+			if test.edit != nil {
+				test.edit(&existing)
+			}
+			options := AppendOptions{Orders: 8, ReturningRate: 0.5}
+			added, returning, err := appendOrders(existing, config, options, now)
+			if err != nil {
+				t.Fatalf("appendOrders: %v", err)
+			}
+			if len(added.Xoxo.Orders) != options.Orders {
+				t.Errorf("added %d orders, want %d", len(added.Xoxo.Orders), options.Orders)
+			}
+
+			checkContinues(t, "orders", keysOf(added.Xoxo.Orders), existing.Xoxo.Orders, "ORD", 3)
+			checkContinues(t, "customers", keysOf(added.Xoxo.Customers), existing.Xoxo.Customers, "CUST", 3)
+			checkContinues(t, "finance transactions", keysOf(added.Xoxo.Finance.Transactions), existing.Xoxo.Finance.Transactions, "FIN", 6)
+
+			codes := make(map[string]bool)
+			for _, order := range existing.Xoxo.Orders {
+				codes[order.Code] = true
+			}
+			for id, order := range added.Xoxo.Orders {
+				if codes[order.Code] {
+					t.Errorf("order %s reuses code %s", id, order.Code)
+				}
+				codes[order.Code] = true
+			}
+			for key := range returning {
+				if _, ok := existing.Xoxo.Customers[key]; !ok {
+					t.Errorf("returning customer %s is not in the dataset", key)
+				}
+			}
+		})
+	}
+}
+
+// copyAppendSources round-trips data through the JSON the append command
+// reads, so each case starts from its own copy.
+func copyAppendSources(t *testing.T, data MockData) MockData {
+	t.Helper()
+	raw, err := json.Marshal(data.Xoxo)
+	if err != nil {
+		t.Fatalf("encode dataset: %v", err)
+	}
+	var tree map[string]any
+	if err := json.Unmarshal(raw, &tree); err != nil {
+		t.Fatalf("encode dataset: %v", err)
+	}
+	existing, err := decodeAppendSources(tree)
+	if err != nil {
+		t.Fatalf("decode dataset: %v", err)
+	}
+	return existing
+}
+
+func keysOf[V any](records map[string]V) []string {
+	keys := make([]string, 0, len(records))
+	for key := range records {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkContinues checks that the added keys are new and number on, without
+// gaps, from the last key of the existing sequence.
+func checkContinues[V any](t *testing.T, what string, added []string, existing map[string]V, prefix string, width int) {
+	t.Helper()
+	next := nextIndex(existing, prefix+"_")
+	for i, key := range added {
+		if _, taken := existing[key]; taken {
+			t.Errorf("%s: added key %s is already in use", what, key)
+		}
+		if want := fmt.Sprintf("%s_%0*d", prefix, width, next+i+1); key != want {
+			t.Errorf("%s: added keys %v, want them to continue from %s_%0*d", what, added, prefix, width, next)
+			return
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"unicode"
+)
+
+// Mobile prefixes (after the 2018 11-to-10-digit conversion) with each
+// carrier's approximate share of subscribers.
+var mobileCarriers = []struct {
+	Name     string
+	Share    int
+	Prefixes []string
+}{
+	{"Viettel", 55, []string{"086", "096", "097", "098", "032", "033", "034", "035", "036", "037", "038", "039"}},
+	{"Vinaphone", 20, []string{"088", "091", "094", "081", "082", "083", "084", "085"}},
+	{"Mobifone", 20, []string{"089", "090", "093", "070", "076", "077", "078", "079"}},
+	{"Vietnamobile", 4, []string{"092", "056", "058"}},
+	{"Gmobile", 1, []string{"099", "059"}},
+}
+
+var emailDomains = []struct {
+	Domain string
+	Share  int
+}{
+	{"gmail.com", 80},
+	{"yahoo.com", 8},
+	{"icloud.com", 7},
+	{"outlook.com", 5},
+}
+
+// vietnameseAccents is the table of removeAccentsAndSpaces in
+// src/app/api/admin/migrate-database/route.ts; keep the two in sync.
+var vietnameseAccents = map[rune]rune{
+	'à': 'a', 'á': 'a', 'ạ': 'a', 'ả': 'a', 'ã': 'a',
+	'â': 'a', 'ầ': 'a', 'ấ': 'a', 'ậ': 'a', 'ẩ': 'a', 'ẫ': 'a',
+	'ă': 'a', 'ằ': 'a', 'ắ': 'a', 'ặ': 'a', 'ẳ': 'a', 'ẵ': 'a',
+	'è': 'e', 'é': 'e', 'ẹ': 'e', 'ẻ': 'e', 'ẽ': 'e',
+	'ê': 'e', 'ề': 'e', 'ế': 'e', 'ệ': 'e', 'ể': 'e', 'ễ': 'e',
+	'ì': 'i', 'í': 'i', 'ị': 'i', 'ỉ': 'i', 'ĩ': 'i',
+	'ò': 'o', 'ó': 'o', 'ọ': 'o', 'ỏ': 'o', 'õ': 'o',
+	'ô': 'o', 'ồ': 'o', 'ố': 'o', 'ộ': 'o', 'ổ': 'o', 'ỗ': 'o',
+	'ơ': 'o', 'ờ': 'o', 'ớ': 'o', 'ợ': 'o', 'ở': 'o', 'ỡ': 'o',
+	'ù': 'u', 'ú': 'u', 'ụ': 'u', 'ủ': 'u', 'ũ': 'u',
+	'ư': 'u', 'ừ': 'u', 'ứ': 'u', 'ự': 'u', 'ử': 'u', 'ữ': 'u',
+	'ỳ': 'y', 'ý': 'y', 'ỵ': 'y', 'ỷ': 'y', 'ỹ': 'y',
+	'đ': 'd',
+	'À': 'A', 'Á': 'A', 'Ạ': 'A', 'Ả': 'A', 'Ã': 'A',
+	'Â': 'A', 'Ầ': 'A', 'Ấ': 'A', 'Ậ': 'A', 'Ẩ': 'A', 'Ẫ': 'A',
+	'Ă': 'A', 'Ằ': 'A', 'Ắ': 'A', 'Ặ': 'A', 'Ẳ': 'A', 'Ẵ': 'A',
+	'È': 'E', 'É': 'E', 'Ẹ': 'E', 'Ẻ': 'E', 'Ẽ': 'E',
+	'Ê': 'E', 'Ề': 'E', 'Ế': 'E', 'Ệ': 'E', 'Ể': 'E', 'Ễ': 'E',
+	'Ì': 'I', 'Í': 'I', 'Ị': 'I', 'Ỉ': 'I', 'Ĩ': 'I',
+	'Ò': 'O', 'Ó': 'O', 'Ọ': 'O', 'Ỏ': 'O', 'Õ': 'O',
+	'Ô': 'O', 'Ồ': 'O', 'Ố': 'O', 'Ộ': 'O', 'Ổ': 'O', 'Ỗ': 'O',
+	'Ơ': 'O', 'Ờ': 'O', 'Ớ': 'O', 'Ợ': 'O', 'Ở': 'O', 'Ỡ': 'O',
+	'Ù': 'U', 'Ú': 'U', 'Ụ': 'U', 'Ủ': 'U', 'Ũ': 'U',
+	'Ư': 'U', 'Ừ': 'U', 'Ứ': 'U', 'Ự': 'U', 'Ử': 'U', 'Ữ': 'U',
+	'Ỳ': 'Y', 'Ý': 'Y', 'Ỵ': 'Y', 'Ỷ': 'Y', 'Ỹ': 'Y',
+	'Đ': 'D',
+}
+
+// removeAccentsAndSpaces folds Vietnamese letters to ASCII and drops
+// whitespace, the same way the migrate-database route does.
+func removeAccentsAndSpaces(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		if folded, ok := vietnameseAccents[r]; ok {
+			r = folded
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ContactBook hands out phone numbers and email addresses that are unique
+// within one generated dataset. Phone is the customer lookup key in the app.
+type ContactBook struct {
+	phones      map[string]bool
+	emails      map[string]bool
+	issuedPhone []string
+}
+
+func newContactBook() *ContactBook {
+	return &ContactBook{
+		phones: make(map[string]bool),
+		emails: make(map[string]bool),
+	}
+}
+
+// Reserve marks fixed contacts (such as the fixed members) as taken.
+func (c *ContactBook) Reserve(phone, email string) {
+	if phone != "" {
+		c.phones[phone] = true
+	}
+	if email != "" {
+		c.emails[strings.ToLower(email)] = true
+	}
+}
+
+// Phone returns an unused 10-digit mobile number on a real carrier prefix.
+func (c *ContactBook) Phone() string {
+	for {
+		carrier := mobileCarriers[len(mobileCarriers)-1]
+		pick := rand.Intn(100)
+		for _, candidate := range mobileCarriers {
+			if pick < candidate.Share {
+				carrier = candidate
+				break
+			}
+			pick -= candidate.Share
+		}
+		phone := fmt.Sprintf("%s%07d", carrier.Prefixes[rand.Intn(len(carrier.Prefixes))], rand.Intn(10000000))
+		if !c.phones[phone] {
+			c.phones[phone] = true
+			c.issuedPhone = append(c.issuedPhone, phone)
+			return phone
+		}
+	}
+}
+
+// DuplicatePhone returns a number already given to someone else. It backs the
+// opt-in duplicate-phone scenario and falls back to a fresh number when none
+// has been issued yet.
+func (c *ContactBook) DuplicatePhone() string {
+	if len(c.issuedPhone) == 0 {
+		return c.Phone()
+	}
+	return c.issuedPhone[rand.Intn(len(c.issuedPhone))]
+}
+
+// Email returns an unused address built from the accent-folded full name,
+// e.g. "Đặng Thị Hồng" becomes hongdang@, dangthihong@ or hong.dang@.
+func (c *ContactBook) Email(fullName string) string {
+	parts := strings.Fields(strings.ToLower(fullName))
+	for i, part := range parts {
+		parts[i] = removeAccentsAndSpaces(part)
+	}
+	if len(parts) == 0 {
+		parts = []string{"khachhang"}
+	}
+	surname, given := parts[0], parts[len(parts)-1]
+
+	var local string
+	switch rand.Intn(3) {
+	case 0:
+		local = given + surname
+	case 1:
+		local = strings.Join(parts, "")
+	default:
+		local = given + "." + surname
+	}
+	if rand.Float32() < 0.4 {
+		local += fmt.Sprintf("%02d", (80+rand.Intn(26))%100)
+	}
+
+	domain := emailDomains[0].Domain
+	pick := rand.Intn(100)
+	for _, candidate := range emailDomains {
+		if pick < candidate.Share {
+			domain = candidate.Domain
+			break
+		}
+		pick -= candidate.Share
+	}
+
+	email := local + "@" + domain
+	for n := 2; c.emails[email]; n++ {
+		email = fmt.Sprintf("%s%d@%s", local, n, domain)
+	}
+	c.emails[email] = true
+	return email
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Orders per customer follow a discrete Pareto distribution: about two thirds
+// of customers come once, a few regulars bring in a large share of the orders.
+const (
+	orderCountAlpha      = 1.5
+	maxOrdersPerCustomer = 12
+)
+
+// A customer returns at least repeatMinDays after an order, on average
+// repeatMinDays+repeatMeanExtraDays.
+const (
+	repeatMinDays       = 14
+	repeatMeanExtraDays = 46
+)
+
+// lapsedAfterDays is how long without an order before a customer counts as
+// lapsed and due for a care call.
+const lapsedAfterDays = 120
+
+// CustomerPlan is one customer's purchase history: the indices of their
+// orders in date order, and whether they have stopped coming.
+type CustomerPlan struct {
+	Orders []int
+	Lapsed bool
+}
+
+// Customer groups assigned from purchase history, highest priority first.
+var customerGroupTiers = []struct {
+	Code string
+	Name string
+}{
+	{"GROUP_VIP", "Khách hàng VIP"},
+	{"GROUP_LAPSED", "Khách hàng cần chăm sóc lại"},
+	{"GROUP_LOYAL", "Khách hàng thân thiết"},
+}
+
+// VIP customers have spent at least vipSpend on completed orders or ordered
+// at least vipOrders times.
+const (
+	vipSpend  = 10000000
+	vipOrders = 4
+)
+
+func paretoOrderCount() int {
+	count := int(math.Pow(1-rand.Float64(), -1/orderCountAlpha))
+	return min(max(count, 1), maxOrdersPerCustomer)
+}
+
+func repeatInterval() int64 {
+	return int64(repeatMinDays+rand.ExpFloat64()*repeatMeanExtraDays) * dayMillis
+}
+
+// planCustomers shares orders out among customers. Each customer is given a
+// number of orders up front; orders are then handed out in date order, either
+// to a returning customer whose repeat interval has passed or to a new one, so
+// that repeat orders make up the planned share. lapsedRate of the customers
+// whose first order is old enough stop coming lapsedAfterDays before now.
+func planCustomers(orderDates []int64, lapsedRate float64, now int64) []CustomerPlan {
+	byDate := make([]int, len(orderDates))
+	for i := range byDate {
+		byDate[i] = i
+	}
+	sort.SliceStable(byDate, func(a, b int) bool { return orderDates[byDate[a]] < orderDates[byDate[b]] })
+
+	var targets []int
+	for total := 0; total < len(orderDates); {
+		count := min(paretoOrderCount(), len(orderDates)-total)
+		targets = append(targets, count)
+		total += count
+	}
+
+	type customerState struct {
+		target     int
+		nextOrder  int64
+		lapsed     bool
+		orderIndex []int
+	}
+	var customers []*customerState
+	repeatOrdersLeft := len(orderDates) - len(targets)
+	lapsedBefore := now - lapsedAfterDays*dayMillis
+
+	for k, orderIdx := range byDate {
+		at := orderDates[orderIdx]
+
+		var returning []*customerState
+		for _, c := range customers {
+			if len(c.orderIndex) < c.target && c.nextOrder <= at && !(c.lapsed && at >= lapsedBefore) {
+				returning = append(returning, c)
+			}
+		}
+
+		var customer *customerState
+		ordersLeft := len(byDate) - k
+		if len(returning) > 0 && rand.Intn(ordersLeft) < repeatOrdersLeft {
+			customer = returning[rand.Intn(len(returning))]
+			repeatOrdersLeft--
+		} else {
+			target := 1
+			if len(customers) < len(targets) {
+				target = targets[len(customers)]
+			}
+			customer = &customerState{
+				target: target,
+				lapsed: at < lapsedBefore && rand.Float64() < lapsedRate,
+			}
+			customers = append(customers, customer)
+		}
+		customer.orderIndex = append(customer.orderIndex, orderIdx)
+		customer.nextOrder = at + repeatInterval()
+	}
+
+	plans := make([]CustomerPlan, len(customers))
+	for i, c := range customers {
+		plans[i] = CustomerPlan{Orders: c.orderIndex, Lapsed: c.lapsed}
+	}
+	return plans
+}
+
+// newCustomer creates an individual customer with contacts of their own,
+// first seen at their first order. duplicatePhoneRate is the chance that they
+// are given a phone number already in use.
+func newCustomer(code string, contacts *ContactBook, duplicatePhoneRate float64, firstOrderAt, lastOrderAt int64) Customer {
+	gender := randomGender()
+	name := randomName(gender)
+	address := addresses.Random()
+	customer := Customer{
+		Code:           code,
+		Name:           name,
+		Phone:          contacts.Phone(),
+		Email:          contacts.Email(name),
+		Address:        address.Full(),
+		CustomerSource: customerSources[rand.Intn(len(customerSources))],
+		Province:       address.ProvinceCode,
+		District:       address.DistrictCode,
+		Ward:           address.WardCode,
+		CustomerType:   "individual",
+		Gender:         gender,
+		CreatedAt:      firstOrderAt,
+		UpdatedAt:      lastOrderAt,
+	}
+	// Opt-in scenario: a different person registered under a phone already in use
+	if rand.Float64() < duplicatePhoneRate {
+		customer.Phone = contacts.DuplicatePhone()
+	}
+	return customer
+}
+
+// customerGroup picks the group a customer belongs to from their orders, or
+// "" when no tier applies.
+func customerGroup(orders []FirebaseOrderData, lapsed bool) string {
+	spend, count := 0, 0
+	for _, order := range orders {
+		if order.Status == "cancelled" {
+			continue
+		}
+		count++
+		if order.Status == "completed" {
+			spend += order.TotalAmount
+		}
+	}
+	switch {
+	case spend >= vipSpend || count >= vipOrders:
+		return customerGroupTiers[0].Code
+	case lapsed:
+		return customerGroupTiers[1].Code
+	case count >= 2:
+		return customerGroupTiers[2].Code
+	}
+	return ""
+}
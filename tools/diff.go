@@ -0,0 +1,432 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiffOptions configures how the diff command compares two datasets.
+type DiffOptions struct {
+	Root string
+	// ByKey matches records on their database key rather than their code.
+	ByKey bool
+	// Ignore lists field names left out of field-level diffs, e.g. updatedAt
+	// when comparing two generator runs.
+	Ignore []string
+	// Collections limits the diff to some collections; none compares all.
+	Collections []string
+	// Summary prints only the counts and the monetary deltas.
+	Summary bool
+}
+
+func (o *DiffOptions) register(flags *flag.FlagSet) {
+	flags.StringVar(&o.Root, "root", "xoxo", "path of the tree to compare in both files")
+	flags.BoolVar(&o.ByKey, "by-key", false, "match records on their database key instead of their code")
+	flags.Func("ignore", "field name to leave out of field diffs, e.g. updatedAt; repeatable", func(value string) error {
+		o.Ignore = append(o.Ignore, value)
+		return nil
+	})
+	flags.Func("collection", "collection to compare, e.g. orders or finance/transactions; repeatable (default all)", func(value string) error {
+		path := strings.Trim(value, "/")
+		if path == "" {
+			return fmt.Errorf("empty collection")
+		}
+		o.Collections = append(o.Collections, path)
+		return nil
+	})
+	flags.BoolVar(&o.Summary, "summary", false, "print only counts per collection and the monetary summary")
+}
+
+// FieldChange is one leaf of a record that differs; a field that is missing
+// on one side has a nil value there.
+type FieldChange struct {
+	Path     string
+	Old, New any
+}
+
+// RecordChange is a record present in both datasets with different fields.
+type RecordChange struct {
+	ID     string
+	Fields []FieldChange
+}
+
+// CollectionDiff is what changed in one collection, by record identity.
+type CollectionDiff struct {
+	Name    string
+	Added   []string
+	Removed []string
+	Changed []RecordChange
+}
+
+func (d CollectionDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// loadDataset reads a generated or exported file and returns the tree at root.
+func loadDataset(path, root string) (map[string]any, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var data any
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	tree, ok := getAt(data, splitPath(root)).(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s has no %s tree", path, root)
+	}
+	return tree, nil
+}
+
+// diffCollections lists the collections to compare: the generator's, then
+// any other object of records either dataset has, such as those only the app
+// writes.
+func diffCollections(before, after map[string]any) []string {
+	var names []string
+	for name := range collectionSchemas() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	known := make(map[string]bool)
+	for _, name := range names {
+		known[splitPath(name)[0]] = true
+	}
+	var extra []string
+	for _, tree := range []map[string]any{before, after} {
+		for name, value := range tree {
+			if known[name] || slices.Contains(extra, name) || !isRecordMap(value) {
+				continue
+			}
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	return append(names, extra...)
+}
+
+func isRecordMap(value any) bool {
+	records, ok := value.(map[string]any)
+	if !ok || len(records) == 0 {
+		return false
+	}
+	for _, record := range records {
+		if _, ok := record.(map[string]any); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// recordIndexes keys the records of a collection in both datasets by the
+// same identity: their code when every record on both sides has one of its
+// own, otherwise, or when byKey is set, their database key. Choosing for each
+// side alone would report every record of a side that switched as removed
+// and added again.
+func recordIndexes(before, after map[string]any, byKey bool) (map[string]any, map[string]any) {
+	if !byKey {
+		oldIndex, oldUnique := indexByCode(before)
+		newIndex, newUnique := indexByCode(after)
+		if oldUnique && newUnique {
+			return oldIndex, newIndex
+		}
+	}
+	return before, after
+}
+
+// indexByCode keys records by their code, reporting false when some record
+// has no code or shares it with another.
+func indexByCode(records map[string]any) (map[string]any, bool) {
+	index := make(map[string]any, len(records))
+	for _, record := range records {
+		code, _ := getAt(record, []string{"code"}).(string)
+		if code == "" || index[code] != nil {
+			return nil, false
+		}
+		index[code] = record
+	}
+	return index, true
+}
+
+// flattenRecord maps every leaf under value to its slash-separated path.
+func flattenRecord(prefix string, value any, ignore []string, leaves map[string]any) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "/" + key
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if slices.Contains(ignore, key) {
+				continue
+			}
+			flattenRecord(join(key), child, ignore, leaves)
+		}
+	case []any:
+		for i, child := range v {
+			flattenRecord(join(strconv.Itoa(i)), child, ignore, leaves)
+		}
+	default:
+		if value != nil {
+			leaves[prefix] = value
+		}
+	}
+}
+
+// recordChanges compares two versions of a record leaf by leaf.
+func recordChanges(before, after any, ignore []string) []FieldChange {
+	oldLeaves, newLeaves := make(map[string]any), make(map[string]any)
+	flattenRecord("", before, ignore, oldLeaves)
+	flattenRecord("", after, ignore, newLeaves)
+	paths := make(map[string]bool)
+	for path := range oldLeaves {
+		paths[path] = true
+	}
+	for path := range newLeaves {
+		paths[path] = true
+	}
+	var changes []FieldChange
+	for path := range paths {
+		if oldLeaves[path] != newLeaves[path] {
+			changes = append(changes, FieldChange{path, oldLeaves[path], newLeaves[path]})
+		}
+	}
+	slices.SortFunc(changes, func(a, b FieldChange) int { return compareKeys(a.Path, b.Path) })
+	return changes
+}
+
+// diffCollection compares one collection of the two datasets.
+func diffCollection(name string, before, after map[string]any, options DiffOptions) CollectionDiff {
+	oldRecords, _ := getAt(before, splitPath(name)).(map[string]any)
+	newRecords, _ := getAt(after, splitPath(name)).(map[string]any)
+	oldIndex, newIndex := recordIndexes(oldRecords, newRecords, options.ByKey)
+
+	diff := CollectionDiff{Name: name}
+	for id, record := range newIndex {
+		previous, ok := oldIndex[id]
+		if !ok {
+			diff.Added = append(diff.Added, id)
+			continue
+		}
+		if fields := recordChanges(previous, record, options.Ignore); len(fields) > 0 {
+			diff.Changed = append(diff.Changed, RecordChange{id, fields})
+		}
+	}
+	for id := range oldIndex {
+		if _, ok := newIndex[id]; !ok {
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+	sortKeys(diff.Added)
+	sortKeys(diff.Removed)
+	slices.SortFunc(diff.Changed, func(a, b RecordChange) int { return compareKeys(a.ID, b.ID) })
+	return diff
+}
+
+// MoneyTotal is one monetary total of both datasets.
+type MoneyTotal struct {
+	Name     string
+	Old, New float64
+}
+
+// moneyTotals sums the amounts that matter when reviewing a dataset change:
+// what orders are worth and what was paid on them, refunds, supplier
+// payments, the finance ledger and the value of stock at import prices.
+func moneyTotals(before, after map[string]any) []MoneyTotal {
+	sum := func(tree map[string]any, collection, field string, match func(record map[string]any) bool) float64 {
+		records, _ := getAt(tree, splitPath(collection)).(map[string]any)
+		total := 0.0
+		for _, value := range records {
+			record, _ := value.(map[string]any)
+			if match != nil && !match(record) {
+				continue
+			}
+			amount, _ := record[field].(float64)
+			total += amount
+		}
+		return total
+	}
+	ofType := func(txnType string) func(map[string]any) bool {
+		return func(record map[string]any) bool { return record["type"] == txnType }
+	}
+	stockValue := func(tree map[string]any) float64 {
+		materials, _ := tree["materials"].(map[string]any)
+		total := 0.0
+		for _, value := range materials {
+			material, _ := value.(map[string]any)
+			quantity, _ := material["stockQuantity"].(float64)
+			price, _ := material["importPrice"].(float64)
+			total += quantity * price
+		}
+		return total
+	}
+	totals := []struct {
+		name  string
+		total func(tree map[string]any) float64
+	}{
+		{"order value", func(t map[string]any) float64 { return sum(t, "orders", "totalAmount", nil) }},
+		{"order payments", func(t map[string]any) float64 { return sum(t, "orders", "totalPaidAmount", nil) }},
+		{"order debt", func(t map[string]any) float64 { return sum(t, "orders", "remainingDebt", nil) }},
+		{"refunds", func(t map[string]any) float64 { return sum(t, "refunds", "amount", nil) }},
+		{"supplier payments", func(t map[string]any) float64 { return sum(t, "supplier_payments", "amount", nil) }},
+		{"finance income", func(t map[string]any) float64 { return sum(t, "finance/transactions", "amount", ofType("income")) }},
+		{"finance expense", func(t map[string]any) float64 { return sum(t, "finance/transactions", "amount", ofType("expense")) }},
+		{"stock value", stockValue},
+	}
+	result := make([]MoneyTotal, len(totals))
+	for i, total := range totals {
+		result[i] = MoneyTotal{total.name, total.total(before), total.total(after)}
+	}
+	return result
+}
+
+// StockChange is the stock of one material in both datasets.
+type StockChange struct {
+	Material string
+	Unit     string
+	Old, New float64
+}
+
+// stockChanges lists the materials whose stock differs, by code or name.
+func stockChanges(before, after map[string]any, byKey bool) []StockChange {
+	oldMaterials, _ := before["materials"].(map[string]any)
+	newMaterials, _ := after["materials"].(map[string]any)
+	oldIndex, newIndex := recordIndexes(oldMaterials, newMaterials, byKey)
+	ids := make(map[string]bool)
+	for id := range oldIndex {
+		ids[id] = true
+	}
+	for id := range newIndex {
+		ids[id] = true
+	}
+	var changes []StockChange
+	for id := range ids {
+		oldMaterial, _ := oldIndex[id].(map[string]any)
+		newMaterial, _ := newIndex[id].(map[string]any)
+		oldStock, _ := oldMaterial["stockQuantity"].(float64)
+		newStock, _ := newMaterial["stockQuantity"].(float64)
+		if oldStock == newStock {
+			continue
+		}
+		label := id
+		unit, _ := newMaterial["unit"].(string)
+		name, _ := newMaterial["name"].(string)
+		if newMaterial == nil {
+			unit, _ = oldMaterial["unit"].(string)
+			name, _ = oldMaterial["name"].(string)
+		}
+		if name != "" {
+			label += " " + name
+		}
+		changes = append(changes, StockChange{label, unit, oldStock, newStock})
+	}
+	slices.SortFunc(changes, func(a, b StockChange) int { return compareKeys(a.Material, b.Material) })
+	return changes
+}
+
+// formatAmount writes an amount with thousands separators, e.g. 1,250,000.
+func formatAmount(amount float64, signed bool) string {
+	text := strconv.FormatFloat(amount, 'f', -1, 64)
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	} else if signed && amount > 0 {
+		sign = "+"
+	}
+	whole, fraction, hasFraction := strings.Cut(text, ".")
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	if hasFraction {
+		grouped.WriteString("." + fraction)
+	}
+	return sign + grouped.String()
+}
+
+func formatFieldValue(value any) string {
+	if value == nil {
+		return "(none)"
+	}
+	text, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(text)
+}
+
+// diffCommand runs `diff [flags] before.json after.json`: added, removed and
+// changed records per collection, then how the money and the stock moved.
+func diffCommand(args []string) error {
+	var options DiffOptions
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	options.register(flags)
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		return fmt.Errorf("usage: diff [flags] before.json after.json")
+	}
+
+	before, err := loadDataset(flags.Arg(0), options.Root)
+	if err != nil {
+		return err
+	}
+	after, err := loadDataset(flags.Arg(1), options.Root)
+	if err != nil {
+		return err
+	}
+
+	names := options.Collections
+	if len(names) == 0 {
+		names = diffCollections(before, after)
+	}
+	unchanged := 0
+	for _, name := range names {
+		diff := diffCollection(name, before, after, options)
+		if diff.empty() {
+			unchanged++
+			continue
+		}
+		fmt.Printf("%s: %d added, %d removed, %d changed\n", name, len(diff.Added), len(diff.Removed), len(diff.Changed))
+		if options.Summary {
+			continue
+		}
+		for _, id := range diff.Added {
+			fmt.Printf("  + %s\n", id)
+		}
+		for _, id := range diff.Removed {
+			fmt.Printf("  - %s\n", id)
+		}
+		for _, change := range diff.Changed {
+			fmt.Printf("  ~ %s\n", change.ID)
+			for _, field := range change.Fields {
+				fmt.Printf("      %s: %s -> %s\n", field.Path, formatFieldValue(field.Old), formatFieldValue(field.New))
+			}
+		}
+	}
+	if unchanged > 0 {
+		fmt.Printf("%d collection(s) unchanged\n", unchanged)
+	}
+
+	fmt.Printf("\n%-18s %16s %16s %16s\n", "Totals (đ)", "before", "after", "delta")
+	for _, total := range moneyTotals(before, after) {
+		fmt.Printf("%-18s %16s %16s %16s\n", total.Name, formatAmount(total.Old, false), formatAmount(total.New, false), formatAmount(total.New-total.Old, true))
+	}
+	if changes := stockChanges(before, after, options.ByKey); len(changes) > 0 {
+		fmt.Printf("\nStock\n")
+		for _, change := range changes {
+			fmt.Printf("  %-40s %10s -> %-10s %10s %s\n", change.Material, formatAmount(change.Old, false), formatAmount(change.New, false), formatAmount(change.New-change.Old, true), change.Unit)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffCollection(t *testing.T) {
+	order := func(code string, total float64) map[string]any {
+		record := map[string]any{"total": total}
+		if code != "" {
+			record["code"] = code
+		}
+		return record
+	}
+	orders := func(records map[string]any) map[string]any {
+		return map[string]any{"orders": records}
+	}
+	base := func() map[string]any {
+		return map[string]any{
+			"ORD_001": order("ORD001", 100),
+			"ORD_002": order("ORD002", 200),
+			"ORD_003": order("ORD003", 300),
+		}
+	}
+
+	tests := []struct {
+		name        string
+		edit        func(before, after map[string]any)
+		byKey       bool
+		wantAdded   []string
+		wantRemoved []string
+		wantChanged []string
+	}{
+		{name: "unchanged"},
+		{
+			name: "records matched by code across new keys",
+			edit: func(before, after map[string]any) {
+				after["-Nrekeyed"] = after["ORD_002"]
+				delete(after, "ORD_002")
+				after["ORD_004"] = order("ORD004", 400)
+				delete(after, "ORD_003")
+			},
+			wantAdded:   []string{"ORD004"},
+			wantRemoved: []string{"ORD003"},
+		},
+		{
+			name: "code removed from one record",
+			edit: func(before, after map[string]any) {
+				after["ORD_002"] = order("", 200)
+			},
+			wantChanged: []string{"ORD_002"},
+		},
+		{
+			name: "code missing before",
+			edit: func(before, after map[string]any) {
+				before["ORD_001"] = order("", 100)
+				after["ORD_003"] = order("ORD003", 350)
+			},
+			wantChanged: []string{"ORD_001", "ORD_003"},
+		},
+		{
+			name: "shared code",
+			edit: func(before, after map[string]any) {
+				after["ORD_004"] = order("ORD001", 100)
+			},
+			wantAdded: []string{"ORD_004"},
+		},
+		{
+			name: "by key",
+			edit: func(before, after map[string]any) {
+				after["ORD_003"] = order("ORD033", 300)
+			},
+			byKey:       true,
+			wantChanged: []string{"ORD_003"},
+		},
+		{
+			name: "collection only after",
+			edit: func(before, after map[string]any) {
+				for key := range before {
+					delete(before, key)
+				}
+			},
+			wantAdded: []string{"ORD001", "ORD002", "ORD003"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before, after := base(), base()
+			if test.edit != nil {
+				test.edit(before, after)
+			}
+			diff := diffCollection("orders", orders(before), orders(after), DiffOptions{ByKey: test.byKey})
+			var changed []string
+			for _, change := range diff.Changed {
+				changed = append(changed, change.ID)
+			}
+			if !slices.Equal(diff.Added, test.wantAdded) || !slices.Equal(diff.Removed, test.wantRemoved) || !slices.Equal(changed, test.wantChanged) {
+				t.Errorf("added %v, removed %v, changed %v; want added %v, removed %v, changed %v",
+					diff.Added, diff.Removed, changed, test.wantAdded, test.wantRemoved, test.wantChanged)
+			}
+		})
+	}
+}
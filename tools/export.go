@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ExportOptions configures what the export command reads.
+type ExportOptions struct {
+	DatabaseOptions
+	// Paths are the sub-paths of Root to export; none exports the whole tree.
+	Paths []string
+	// PageSize is how many children of a collection are fetched per request.
+	PageSize int
+}
+
+func (o *ExportOptions) register(flags *flag.FlagSet) {
+	o.DatabaseOptions.register(flags)
+	flags.Func("path", "sub-path of the root to export, e.g. orders or members/ADMIN_FIXED_001; repeatable (default the whole tree)", func(value string) error {
+		path := strings.Trim(value, "/")
+		if path == "" {
+			return fmt.Errorf("empty path")
+		}
+		o.Paths = append(o.Paths, path)
+		return nil
+	})
+	flags.IntVar(&o.PageSize, "page-size", 500, "children fetched per request when paging through a collection")
+}
+
+// treeReader reads subtrees over the REST API, paging through big ones.
+type treeReader struct {
+	client   *RTDBClient
+	pageSize int
+	requests int
+}
+
+func (r *treeReader) get(path string, query url.Values) ([]byte, error) {
+	r.requests++
+	return r.client.Do(http.MethodGet, path, query, nil)
+}
+
+// read returns the value at path, or nil when there is nothing there. A
+// shallow query lists the children first; a node with more than pageSize of
+// them is fetched pageSize children at a time in key order.
+func (r *treeReader) read(path string) (json.RawMessage, error) {
+	shallow, err := r.get(path, url.Values{"shallow": {"true"}})
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(string(shallow)) == "null" {
+		return nil, nil
+	}
+	var children map[string]json.RawMessage
+	if err := json.Unmarshal(shallow, &children); err != nil {
+		// A leaf: the shallow response is the value itself.
+		return shallow, nil
+	}
+	if len(children) <= r.pageSize {
+		return r.get(path, nil)
+	}
+
+	keys := make([]string, 0, len(children))
+	for key := range children {
+		keys = append(keys, key)
+	}
+	sortKeys(keys)
+
+	values := make(map[string]json.RawMessage, len(keys))
+	for start := 0; start < len(keys); start += r.pageSize {
+		page := keys[start:min(start+r.pageSize, len(keys))]
+		first, _ := json.Marshal(page[0])
+		last, _ := json.Marshal(page[len(page)-1])
+		body, err := r.get(path, url.Values{
+			"orderBy": {`"$key"`},
+			"startAt": {string(first)},
+			"endAt":   {string(last)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		var pageValues map[string]json.RawMessage
+		if err := json.Unmarshal(body, &pageValues); err != nil && strings.TrimSpace(string(body)) != "null" {
+			return nil, fmt.Errorf("page of /%s from %q: %w", path, page[0], err)
+		}
+		for key, value := range pageValues {
+			values[key] = value
+		}
+	}
+	return json.Marshal(values)
+}
+
+// sortKeys sorts keys the way the database orders them by $key.
+func sortKeys(keys []string) {
+	slices.SortFunc(keys, compareKeys)
+}
+
+// exportTree reads the tree at options.Root, or the selected paths under it,
+// and nests it under the root path, the layout the generator writes. Progress
+// is reported per collection or path.
+func exportTree(options ExportOptions) (map[string]any, error) {
+	if options.PageSize < 1 {
+		return nil, fmt.Errorf("-page-size must be at least 1, got %d", options.PageSize)
+	}
+	client, err := options.client()
+	if err != nil {
+		return nil, err
+	}
+	reader := &treeReader{client: client, pageSize: options.PageSize}
+	root := strings.Trim(options.Root, "/")
+
+	paths := selectedPaths(options.Paths)
+	if len(paths) == 0 {
+		listing, err := reader.get(root, url.Values{"shallow": {"true"}})
+		if err != nil {
+			return nil, err
+		}
+		var collections map[string]json.RawMessage
+		if err := json.Unmarshal(listing, &collections); err != nil || len(collections) == 0 {
+			return nil, fmt.Errorf("nothing to export at /%s", root)
+		}
+		for name := range collections {
+			paths = append(paths, name)
+		}
+		sort.Strings(paths)
+	}
+
+	fmt.Printf("Exporting %s/%s (%d path(s))\n", client.baseURL, root, len(paths))
+	tree := make(map[string]any)
+	for i, path := range paths {
+		before := reader.requests
+		value, err := reader.read(root + "/" + path)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		if value == nil {
+			return nil, fmt.Errorf("nothing to export at /%s/%s", root, path)
+		}
+		setPath(tree, strings.Split(path, "/"), value)
+		fmt.Printf("  [%2d/%d] %-26s %6d KB in %d request(s)\n", i+1, len(paths), path, len(value)/1024, reader.requests-before)
+	}
+
+	segments := strings.Split(root, "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		tree = map[string]any{segments[i]: tree}
+	}
+	return tree, nil
+}
+
+// selectedPaths sorts paths and drops those inside another selected path.
+func selectedPaths(paths []string) []string {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+	var selected []string
+	for _, path := range sorted {
+		if n := len(selected); n > 0 && (path == selected[n-1] || strings.HasPrefix(path, selected[n-1]+"/")) {
+			continue
+		}
+		selected = append(selected, path)
+	}
+	return selected
+}
+
+func setPath(tree map[string]any, segments []string, value json.RawMessage) {
+	for _, segment := range segments[:len(segments)-1] {
+		child, ok := tree[segment].(map[string]any)
+		if !ok {
+			child = make(map[string]any)
+			tree[segment] = child
+		}
+		tree = child
+	}
+	tree[segments[len(segments)-1]] = value
+}
+
+// exportCommand runs `export [flags] [output.json]`.
+func exportCommand(args []string) error {
+	var options ExportOptions
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	options.register(flags)
+	flags.Parse(args)
+
+	tree, err := exportTree(options)
+	if err != nil {
+		return err
+	}
+	jsonData, err := json.MarshalIndent(tree, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal export: %w", err)
+	}
+
+	outputFile := "./export.json"
+	if flags.NArg() > 0 {
+		outputFile = flags.Arg(0)
+	}
+	if err := os.WriteFile(outputFile, jsonData, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", outputFile, err)
+	}
+	fmt.Printf("Exported to %s (%d KB)\n", outputFile, len(jsonData)/1024)
+	return nil
+}
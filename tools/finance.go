@@ -0,0 +1,439 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+)
+
+// Refund statuses cycle through this list so processed refunds, the ones that
+// move money, are always present.
+var refundStatusScenarios = []string{"processed", "pending", "approved", "rejected", "processed", "cancelled"}
+
+var bankNames = []string{"Vietcombank", "Techcombank", "ACB", "MB Bank", "VPBank", "BIDV"}
+
+// Monthly base salary ranges by role, in VND.
+var salaryRanges = map[string][2]int{
+	"worker":      {7000000, 10000000},
+	"sales":       {8000000, 12000000},
+	"admin":       {15000000, 20000000},
+	"development": {18000000, 25000000},
+}
+
+// Payroll for a month is paid on this day of the following month.
+const payday = 5
+
+func randomSalary(role string) int {
+	bounds, ok := salaryRanges[role]
+	if !ok {
+		bounds = salaryRanges["worker"]
+	}
+	return roundVND(bounds[0]+rand.Intn(bounds[1]-bounds[0]+1), 500000)
+}
+
+// orderPayments lists what the customer has paid for an order so far, the
+// deposit included, as OrderForm records it in payments:
+//
+//   - pending: nothing yet
+//   - confirmed, cancelled: the deposit, if one was taken
+//   - in_progress, on_hold: the deposit, sometimes a second installment
+//   - completed: the rest at hand-over, sometimes split or leaving a debt
+func orderPayments(orderID string, order FirebaseOrderData, schedule OrderSchedule, roster *Roster, timeline *Timeline, now int64) []PaymentInfo {
+	var payments []PaymentInfo
+	paid := 0
+	pay := func(amount int, content string, at int64) {
+		if amount <= 0 {
+			return
+		}
+		paidBy := roster.Assign(at, order.CreatedBy, hasRole("sales"))
+		payments = append(payments, PaymentInfo{
+			ID:         fmt.Sprintf("payment_%s_%d", orderID, len(payments)+1),
+			Amount:     amount,
+			Content:    content,
+			PaidAt:     at,
+			PaidBy:     paidBy,
+			PaidByName: roster.Name(paidBy),
+			CreatedAt:  at,
+		})
+		paid += amount
+	}
+
+	if order.IsDepositPaid {
+		pay(order.DepositAmount, "Tiền cọc", schedule.ConfirmedAt)
+	}
+
+	switch order.Status {
+	case "in_progress", "on_hold":
+		if rand.Float32() < 0.3 {
+			pay((order.TotalAmount-paid)/2/50000*50000, "Thanh toán đợt 2", timeline.Between(schedule.ConfirmedAt, now))
+		}
+	case "completed":
+		if rand.Float32() < 0.3 {
+			pay((order.TotalAmount-paid)/2/50000*50000, "Thanh toán đợt 2", timeline.Between(schedule.ConfirmedAt, schedule.DeliveryDate))
+		}
+		debt := 0
+		if rand.Float32() < 0.15 {
+			debt = min(max((order.TotalAmount-paid)/4/50000*50000, 50000), order.TotalAmount-paid)
+		}
+		pay(order.TotalAmount-paid-debt, "Thanh toán phần còn lại khi trả đồ", schedule.DeliveryDate)
+	}
+	return payments
+}
+
+// generateSuppliers creates a supplier record for every supplier name.
+func generateSuppliers(contacts *ContactBook, createdAt int64) map[string]Supplier {
+	suppliers := make(map[string]Supplier, len(supplierNames))
+	for i, name := range supplierNames {
+		suppliers[generateID("SUP", i)] = Supplier{
+			Code:        fmt.Sprintf("NCC-%06d", i+1),
+			Name:        name,
+			Phone:       contacts.Phone(),
+			Address:     addresses.Random().Full(),
+			BankAccount: fmt.Sprintf("%013d", rand.Int63n(1e13)),
+			BankName:    bankNames[rand.Intn(len(bankNames))],
+			Status:      "active",
+			CreatedAt:   createdAt,
+			UpdatedAt:   createdAt,
+		}
+	}
+	return suppliers
+}
+
+// generateSupplierPayments settles every purchase in the inventory ledger
+// within a week of delivery. Opening balances were bought before the history
+// and are not paid again.
+func generateSupplierPayments(suppliers map[string]Supplier, transactions map[string]InventoryTransaction, payer Member, timeline *Timeline) map[string]SupplierPayment {
+	supplierIDs := make(map[string]string, len(suppliers))
+	for id, supplier := range suppliers {
+		supplierIDs[supplier.Name] = id
+	}
+
+	txnCodes := make([]string, 0, len(transactions))
+	for code := range transactions {
+		txnCodes = append(txnCodes, code)
+	}
+	sort.Strings(txnCodes)
+
+	payments := make(map[string]SupplierPayment)
+	for _, txnCode := range txnCodes {
+		txn := transactions[txnCode]
+		if txn.Type != "import" || txn.Reason == openingBalanceReason || txn.TotalAmount <= 0 {
+			continue
+		}
+		supplierID := supplierIDs[txn.Supplier]
+		supplier := suppliers[supplierID]
+		paidAt := timeline.Following(txn.CreatedAt, 7)
+
+		payment := SupplierPayment{
+			Code:          generateID("SPAY", len(payments)),
+			SupplierID:    supplierID,
+			SupplierName:  supplier.Name,
+			Amount:        txn.TotalAmount,
+			PaymentDate:   paidAt,
+			PaymentMethod: paymentMethods[rand.Intn(len(paymentMethods))],
+			Notes:         fmt.Sprintf("Thanh toán phiếu nhập %s", txnCode),
+			CreatedBy:     payer.ID,
+			CreatedByName: payer.Name,
+			CreatedAt:     paidAt,
+			UpdatedAt:     paidAt,
+		}
+		if payment.PaymentMethod == "bank_transfer" {
+			payment.BankAccount = supplier.BankAccount
+			payment.BankName = supplier.BankName
+		}
+		payments[txnCode] = payment
+	}
+	return payments
+}
+
+// buildFinanceLedger projects every money event in the dataset into finance
+// transactions, shaped like the ones FinanceService and the finance page
+// create: order payments, processed refunds, supplier payments, monthly
+// payroll and a few manual entries. IDs are assigned in date order.
+func buildFinanceLedger(data *MockData, supplierPayments map[string]SupplierPayment, numManual int, payer Member, roster *Roster, timeline *Timeline, now int64) map[string]FinanceTransaction {
+	var ledger []FinanceTransaction
+	add := func(txn FinanceTransaction) {
+		txn.CreatedAt = txn.Date
+		txn.UpdatedAt = txn.Date
+		ledger = append(ledger, txn)
+	}
+
+	for orderID, order := range data.Xoxo.Orders {
+		for _, txn := range orderIncome(orderID, order) {
+			add(txn)
+		}
+	}
+
+	for refundID, refund := range data.Xoxo.Refunds {
+		if refund.Status != "processed" {
+			continue
+		}
+		add(FinanceTransaction{
+			Date:          refund.ProcessedDate,
+			Type:          "expense",
+			Category:      "order",
+			Amount:        refund.Amount,
+			Description:   fmt.Sprintf("Hoàn tiền đơn hàng %s", refund.OrderCode),
+			Reference:     refund.OrderCode,
+			SourceID:      refundID,
+			SourceType:    "refund",
+			CreatedBy:     refund.ProcessedBy,
+			CreatedByName: refund.ProcessedByName,
+			Notes:         fmt.Sprintf("Lý do: %s", refund.Reason),
+		})
+	}
+
+	for txnCode, payment := range supplierPayments {
+		txn := data.Xoxo.InventoryTransactions[txnCode]
+		add(FinanceTransaction{
+			Date:          payment.PaymentDate,
+			Type:          "expense",
+			Category:      "inventory",
+			Amount:        payment.Amount,
+			Description:   fmt.Sprintf("Phiếu nhập kho: %s", txn.MaterialName),
+			Reference:     txnCode,
+			SourceID:      txnCode,
+			SourceType:    "inventory",
+			CreatedBy:     payment.CreatedBy,
+			CreatedByName: payment.CreatedByName,
+			Notes:         fmt.Sprintf("Thanh toán %s (%s)", payment.SupplierName, payment.Code),
+		})
+	}
+
+	// Payroll is entered by hand on the finance page, one entry per member
+	// and month: their salaryAmount, prorated for the months they joined or
+	// left.
+	firstMonth := startOfDay(timeline.Start()).AddDate(0, 0, 1-startOfDay(timeline.Start()).Day())
+	for month := firstMonth; ; month = month.AddDate(0, 1, 0) {
+		monthEnd := month.AddDate(0, 1, 0)
+		paydayStart := monthEnd.AddDate(0, 0, payday-1).UnixMilli()
+		if paydayStart+dayMillis > now {
+			break
+		}
+		paidAt := timeline.Between(paydayStart, paydayStart+dayMillis)
+		for _, id := range roster.ids {
+			member := data.Xoxo.Members[id]
+			tenure := roster.Tenure(id)
+			workedFrom := max(tenure.HiredAt, month.UnixMilli())
+			workedTo := monthEnd.UnixMilli()
+			if tenure.LeftAt != 0 {
+				workedTo = min(workedTo, tenure.LeftAt)
+			}
+			if member.SalaryAmount == 0 || workedTo <= workedFrom {
+				continue
+			}
+			amount := member.SalaryAmount
+			if workedTo-workedFrom < monthEnd.UnixMilli()-month.UnixMilli() {
+				daysInMonth := monthEnd.Sub(month).Milliseconds() / dayMillis
+				daysWorked := (workedTo - workedFrom + dayMillis - 1) / dayMillis
+				amount = roundVND(int(int64(amount)*daysWorked/daysInMonth), 10000)
+			}
+			if amount <= 0 {
+				continue
+			}
+			add(manualFinanceTransaction(payer, paidAt, "expense", "salary", amount,
+				fmt.Sprintf("Lương tháng %s - %s", month.Format("01/2006"), member.Name),
+				fmt.Sprintf("SALARY_%s_%s", member.ID, month.Format("200601"))))
+		}
+	}
+
+	for i := 0; i < numManual && len(manualFinanceEntries) > 0; i++ {
+		entry := manualFinanceEntries[rand.Intn(len(manualFinanceEntries))]
+		amount := entry.MinAmount + rand.Intn(entry.MaxAmount-entry.MinAmount+1)
+		at := timeline.Random()
+		add(manualFinanceTransaction(payer, at, entry.Type, entry.Category, roundVND(amount, priceStep(amount)),
+			entry.Description, fmt.Sprintf("MANUAL_%d", at)))
+	}
+
+	sort.SliceStable(ledger, func(a, b int) bool {
+		if ledger[a].Date != ledger[b].Date {
+			return ledger[a].Date < ledger[b].Date
+		}
+		return ledger[a].Reference < ledger[b].Reference
+	})
+	transactions := make(map[string]FinanceTransaction, len(ledger))
+	for i, txn := range ledger {
+		txn.ID = generateFinanceCode(i)
+		transactions[txn.ID] = txn
+	}
+	return transactions
+}
+
+// orderIncome is the income entry OrderForm records for each payment on an
+// order.
+func orderIncome(orderID string, order FirebaseOrderData) []FinanceTransaction {
+	transactions := make([]FinanceTransaction, 0, len(order.Payments))
+	for k, payment := range order.Payments {
+		description := fmt.Sprintf("Thanh toán đơn hàng %s", order.Code)
+		switch {
+		case payment.Content == "Tiền cọc":
+			description = fmt.Sprintf("Tiền cọc đơn hàng %s", order.Code)
+		case k == len(order.Payments)-1 && order.Status == "completed":
+			description = fmt.Sprintf("Số tiền còn lại đơn hàng %s", order.Code)
+		}
+		transactions = append(transactions, FinanceTransaction{
+			Date:          payment.PaidAt,
+			Type:          "income",
+			Category:      "order",
+			Amount:        payment.Amount,
+			Description:   description,
+			Reference:     order.Code,
+			SourceID:      orderID,
+			SourceType:    "order",
+			CreatedBy:     payment.PaidBy,
+			CreatedByName: payment.PaidByName,
+			Notes:         payment.Content,
+		})
+	}
+	return transactions
+}
+
+func manualFinanceTransaction(by Member, at int64, txnType, category string, amount int, description, reference string) FinanceTransaction {
+	return FinanceTransaction{
+		Date:          at,
+		Type:          txnType,
+		Category:      category,
+		Amount:        amount,
+		Description:   description,
+		Reference:     reference,
+		SourceType:    "manual",
+		CreatedBy:     by.ID,
+		CreatedByName: by.Name,
+		IsManual:      true,
+	}
+}
+
+// FinanceReport is the cash position over the history: totals per category
+// and a running balance per day.
+type FinanceReport struct {
+	OpeningBalance int                    `json:"openingBalance"`
+	ClosingBalance int                    `json:"closingBalance"`
+	TotalIncome    int                    `json:"totalIncome"`
+	TotalExpense   int                    `json:"totalExpense"`
+	Categories     []FinanceCategoryTotal `json:"categories"`
+	Days           []FinanceDay           `json:"days"`
+}
+
+type FinanceCategoryTotal struct {
+	Category string `json:"category"`
+	Income   int    `json:"income"`
+	Expense  int    `json:"expense"`
+}
+
+type FinanceDay struct {
+	Date    string `json:"date"`
+	Income  int    `json:"income"`
+	Expense int    `json:"expense"`
+	Closing int    `json:"closing"`
+}
+
+// reconcileFinance checks the finance ledger against the records it was
+// projected from, then rolls it up per category and per day, starting from
+// openingCash. It fails if the balance goes negative on some day: the ledger
+// then spends money the workshop does not have.
+func reconcileFinance(data MockData, openingCash int) (*FinanceReport, error) {
+	expected := map[string]int{}
+	for _, order := range data.Xoxo.Orders {
+		for _, payment := range order.Payments {
+			expected["order"] += payment.Amount
+		}
+		if order.TotalPaidAmount != sumPayments(order.Payments) {
+			return nil, fmt.Errorf("order %s: totalPaidAmount %d does not match its payments", order.Code, order.TotalPaidAmount)
+		}
+	}
+	for _, refund := range data.Xoxo.Refunds {
+		if refund.Status == "processed" {
+			expected["refund"] += refund.Amount
+		}
+	}
+	for _, payment := range data.Xoxo.SupplierPayments {
+		expected["inventory"] += payment.Amount
+	}
+
+	projected := map[string]int{}
+	categories := map[string]*FinanceCategoryTotal{}
+	days := map[string]*FinanceDay{}
+	report := &FinanceReport{}
+	for _, txn := range data.Xoxo.Finance.Transactions {
+		if txn.SourceType != "manual" {
+			projected[txn.SourceType] += txn.Amount
+		}
+
+		category := categories[txn.Category]
+		if category == nil {
+			category = &FinanceCategoryTotal{Category: txn.Category}
+			categories[txn.Category] = category
+		}
+		date := time.UnixMilli(txn.Date).In(vietnamTime).Format("2006-01-02")
+		day := days[date]
+		if day == nil {
+			day = &FinanceDay{Date: date}
+			days[date] = day
+		}
+		switch txn.Type {
+		case "income":
+			category.Income += txn.Amount
+			day.Income += txn.Amount
+			report.TotalIncome += txn.Amount
+		case "expense":
+			category.Expense += txn.Amount
+			day.Expense += txn.Amount
+			report.TotalExpense += txn.Amount
+		default:
+			return nil, fmt.Errorf("finance transaction %s has unknown type %q", txn.ID, txn.Type)
+		}
+	}
+	for source, amount := range expected {
+		if projected[source] != amount {
+			return nil, fmt.Errorf("finance ledger has %d đ from %s records, expected %d đ", projected[source], source, amount)
+		}
+	}
+
+	for _, category := range categories {
+		report.Categories = append(report.Categories, *category)
+	}
+	sort.Slice(report.Categories, func(a, b int) bool { return report.Categories[a].Category < report.Categories[b].Category })
+
+	balance := 0
+	for _, day := range days {
+		report.Days = append(report.Days, *day)
+	}
+	sort.Slice(report.Days, func(a, b int) bool { return report.Days[a].Date < report.Days[b].Date })
+	for i := range report.Days {
+		balance += report.Days[i].Income - report.Days[i].Expense
+		report.Days[i].Closing = balance
+	}
+
+	report.OpeningBalance = openingCash
+	for i := range report.Days {
+		report.Days[i].Closing += report.OpeningBalance
+		if report.Days[i].Closing < 0 {
+			return nil, fmt.Errorf("cash balance falls to %d đ on %s; an opening balance of %d đ (-opening-cash) does not cover the expenses",
+				report.Days[i].Closing, report.Days[i].Date, openingCash)
+		}
+	}
+	report.ClosingBalance = report.OpeningBalance + balance
+	return report, nil
+}
+
+func sumPayments(payments []PaymentInfo) int {
+	total := 0
+	for _, payment := range payments {
+		total += payment.Amount
+	}
+	return total
+}
+
+func writeFinanceReport(report *FinanceReport, path string) error {
+	raw, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return fmt.Errorf("marshal finance report: %w", err)
+	}
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		return fmt.Errorf("write finance report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReconcileFinance(t *testing.T) {
+	at := func(day int) int64 {
+		return time.Date(2025, 3, day, 10, 0, 0, 0, vietnamTime).UnixMilli()
+	}
+	// ledger is an order paid 500k on day 1, a processed refund of 100k on
+	// day 2 and a supplier payment of 300k on day 3.
+	ledger := func() MockData {
+		var data MockData
+		data.Xoxo.Orders = map[string]FirebaseOrderData{
+			"ORD_001": {Code: "ORD001", Payments: []PaymentInfo{{Amount: 500000}}, TotalPaidAmount: 500000},
+		}
+		data.Xoxo.Refunds = map[string]RefundRequest{
+			"REF_001": {Status: "processed", Amount: 100000},
+		}
+		data.Xoxo.SupplierPayments = map[string]SupplierPayment{
+			"SP_001": {Amount: 300000},
+		}
+		data.Xoxo.Finance.Transactions = map[string]FinanceTransaction{
+			"FIN_000001": {ID: "FIN_000001", Date: at(1), Type: "income", Category: "Doanh thu", Amount: 500000, SourceType: "order"},
+			"FIN_000002": {ID: "FIN_000002", Date: at(2), Type: "expense", Category: "Hoàn tiền", Amount: 100000, SourceType: "refund"},
+			"FIN_000003": {ID: "FIN_000003", Date: at(3), Type: "expense", Category: "Nguyên liệu", Amount: 300000, SourceType: "inventory"},
+		}
+		return data
+	}
+
+	tests := []struct {
+		name        string
+		openingCash int
+		edit        func(data *MockData)
+		wantErr     string
+		wantClosing []int
+	}{
+		{
+			name:        "balanced",
+			openingCash: 0,
+			wantClosing: []int{500000, 400000, 100000},
+		},
+		{
+			name:        "opening cash carried through",
+			openingCash: 1000000,
+			wantClosing: []int{1500000, 1400000, 1100000},
+		},
+		{
+			name:        "manual entries are not reconciled against records",
+			openingCash: 0,
+			edit: func(data *MockData) {
+				data.Xoxo.Finance.Transactions["FIN_000004"] = FinanceTransaction{ID: "FIN_000004", Date: at(3), Type: "expense", Category: "Điện nước", Amount: 50000, SourceType: "manual"}
+			},
+			wantClosing: []int{500000, 400000, 50000},
+		},
+		{
+			name:        "balance falls below zero",
+			openingCash: 0,
+			edit: func(data *MockData) {
+				txn := data.Xoxo.Finance.Transactions["FIN_000003"]
+				txn.Date = at(1)
+				data.Xoxo.Finance.Transactions["FIN_000003"] = txn
+				txn = data.Xoxo.Finance.Transactions["FIN_000001"]
+				txn.Date = at(2)
+				data.Xoxo.Finance.Transactions["FIN_000001"] = txn
+			},
+			wantErr: "cash balance falls to -300000 đ on 2025-03-01",
+		},
+		{
+			name:        "missing order income",
+			openingCash: 0,
+			edit: func(data *MockData) {
+				delete(data.Xoxo.Finance.Transactions, "FIN_000001")
+			},
+			wantErr: "finance ledger has 0 đ from order records, expected 500000 đ",
+		},
+		{
+			name:        "pending refunds are not expected in the ledger",
+			openingCash: 0,
+			edit: func(data *MockData) {
+				data.Xoxo.Refunds["REF_002"] = RefundRequest{Status: "pending", Amount: 200000}
+			},
+			wantClosing: []int{500000, 400000, 100000},
+		},
+		{
+			name:        "paid amount out of line with payments",
+			openingCash: 0,
+			edit: func(data *MockData) {
+				order := data.Xoxo.Orders["ORD_001"]
+				order.TotalPaidAmount = 400000
+				data.Xoxo.Orders["ORD_001"] = order
+			},
+			wantErr: "order ORD001: totalPaidAmount 400000 does not match its payments",
+		},
+		{
+			name:        "unknown type",
+			openingCash: 0,
+			edit: func(data *MockData) {
+				data.Xoxo.Finance.Transactions["FIN_000004"] = FinanceTransaction{ID: "FIN_000004", Date: at(3), Type: "transfer", SourceType: "manual"}
+			},
+			wantErr: `finance transaction FIN_000004 has unknown type "transfer"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := ledger()
+			if test.edit != nil {
+				test.edit(&data)
+			}
+			report, err := reconcileFinance(data, test.openingCash)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("reconcileFinance error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("reconcileFinance: %v", err)
+			}
+			var closing []int
+			for _, day := range report.Days {
+				closing = append(closing, day.Closing)
+			}
+			if !slices.Equal(closing, test.wantClosing) {
+				t.Fatalf("closing balances = %v, want %v", closing, test.wantClosing)
+			}
+			if want := test.wantClosing[len(test.wantClosing)-1]; report.ClosingBalance != want {
+				t.Errorf("ClosingBalance = %d, want %d", report.ClosingBalance, want)
+			}
+			if report.OpeningBalance != test.openingCash {
+				t.Errorf("OpeningBalance = %d, want %d", report.OpeningBalance, test.openingCash)
+			}
+		})
+	}
+}
+
+func TestFinanceLedgerPayroll(t *testing.T) {
+	useDefaultVocabulary(t)
+	data := generateMockData(defaultConfig)
+	if _, err := reconcileFinance(data, defaultConfig.OpeningCashBalance); err != nil {
+		t.Fatalf("default opening cash does not cover the default dataset: %v", err)
+	}
+
+	// Members are paid their salaryAmount for every month they worked it
+	// all, and less for the months they joined or left in.
+	full := map[string]int{}
+	for _, txn := range data.Xoxo.Finance.Transactions {
+		reference, isPayroll := strings.CutPrefix(txn.Reference, "SALARY_")
+		if !isPayroll {
+			continue
+		}
+		memberID := reference[:strings.LastIndex(reference, "_")]
+		member, ok := data.Xoxo.Members[memberID]
+		if !ok {
+			t.Fatalf("%s pays unknown member %s", txn.ID, memberID)
+		}
+		switch {
+		case txn.Amount == member.SalaryAmount:
+			full[memberID]++
+		case txn.Amount <= 0 || txn.Amount > member.SalaryAmount:
+			t.Errorf("%s pays %s %d đ, outside their salary of %d đ", txn.ID, memberID, txn.Amount, member.SalaryAmount)
+		}
+	}
+	if len(full) == 0 {
+		t.Fatalf("no member was paid a full month's salary")
+	}
+	for id, member := range data.Xoxo.Members {
+		tenure := tenureOf(member)
+		if member.SalaryAmount > 0 && tenure.LeftAt == 0 && tenure.HiredAt < time.Now().AddDate(0, -3, 0).UnixMilli() && full[id] == 0 {
+			t.Errorf("member %s, employed for months, was never paid a full month", id)
+		}
+	}
+}
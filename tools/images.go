@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const defaultStorageEmulatorHost = "127.0.0.1:9199"
+
+// Placeholder images are rendered at this size. Labels use placeholderFont
+// scaled up by labelScale, titles by titleScale.
+const (
+	placeholderWidth  = 640
+	placeholderHeight = 480
+	labelScale        = 4
+	titleScale        = 6
+)
+
+// Stage titles and band colours by image kind.
+var placeholderStages = map[string]struct {
+	Title string
+	Band  color.RGBA
+}{
+	"before":   {"TRUOC / BEFORE", color.RGBA{0x4a, 0x55, 0x68, 0xff}},
+	"after":    {"SAU / AFTER", color.RGBA{0x2f, 0x85, 0x5a, 0xff}},
+	"material": {"VAT TU", color.RGBA{0x2b, 0x6c, 0xb0, 0xff}},
+	"task":     {"CONG VIEC", color.RGBA{0x6b, 0x46, 0xc1, 0xff}},
+}
+
+// ImageOptions configures the Storage emulator the seed command uploads to.
+type ImageOptions struct {
+	Enabled      bool
+	EmulatorHost string
+	// Bucket defaults to the project's default bucket.
+	Bucket string
+}
+
+func (o *ImageOptions) register(flags *flag.FlagSet) {
+	emulatorHost := os.Getenv("FIREBASE_STORAGE_EMULATOR_HOST")
+	if emulatorHost == "" {
+		emulatorHost = defaultStorageEmulatorHost
+	}
+	flags.BoolVar(&o.Enabled, "images", true, "render placeholder images and upload them to the Storage emulator")
+	flags.StringVar(&o.EmulatorHost, "storage-emulator-host", emulatorHost, "Storage emulator host ($FIREBASE_STORAGE_EMULATOR_HOST)")
+	flags.StringVar(&o.Bucket, "bucket", "", "Storage bucket (default <project>.appspot.com)")
+}
+
+// ImageAsset is a placeholder image to render: where it is stored and what
+// it is labelled with.
+type ImageAsset struct {
+	Path   string
+	Stage  string
+	Labels []string
+}
+
+// bucket returns the bucket to upload to, by default the project's.
+func (o ImageOptions) bucket(projectID string) string {
+	if o.Bucket == "" {
+		return projectID + ".appspot.com"
+	}
+	return o.Bucket
+}
+
+// ImageStore collects the placeholder images referenced by the generated
+// data. Until they are uploaded, references point at the Storage emulator
+// without a download token.
+type ImageStore struct {
+	host   string
+	bucket string
+	assets map[string]ImageAsset
+}
+
+func newImageStore(host, bucket string) *ImageStore {
+	return &ImageStore{host: host, bucket: bucket, assets: make(map[string]ImageAsset)}
+}
+
+// imageStore is configured by main before generation; the default points at
+// the Storage emulator of the demo project.
+var imageStore = newImageStore(defaultStorageEmulatorHost, defaultProjectID+".appspot.com")
+
+// Add registers a placeholder image at a storage path and returns its URL.
+func (s *ImageStore) Add(path, stage string, labels ...string) string {
+	s.assets[path] = ImageAsset{Path: path, Stage: stage, Labels: labels}
+	return s.objectURL(path, "")
+}
+
+func (s *ImageStore) objectURL(path, token string) string {
+	query := url.Values{"alt": {"media"}}
+	if token != "" {
+		query.Set("token", token)
+	}
+	return fmt.Sprintf("http://%s/v0/b/%s/o/%s?%s", s.host, s.bucket, url.PathEscape(path), query.Encode())
+}
+
+// Upload renders every registered image, uploads it to the Storage emulator
+// and returns the download URL, with its token, for each URL handed out by Add.
+func (s *ImageStore) Upload(retries int) (map[string]string, error) {
+	paths := make([]string, 0, len(s.assets))
+	for path := range s.assets {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	urls := make(map[string]string, len(paths))
+	for _, path := range paths {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, renderPlaceholder(s.assets[path])); err != nil {
+			return nil, fmt.Errorf("render %s: %w", path, err)
+		}
+
+		endpoint := fmt.Sprintf("http://%s/v0/b/%s/o?%s", s.host, s.bucket, url.Values{"name": {path}, "uploadType": {"media"}}.Encode())
+		respBody, err := doWithRetries(client, retries, func() (*http.Request, error) {
+			req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Content-Type", "image/png")
+			req.Header.Set("Authorization", "Bearer owner")
+			return req, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("upload %s: %w", path, err)
+		}
+		var object struct {
+			DownloadTokens string `json:"downloadTokens"`
+		}
+		if err := json.Unmarshal(respBody, &object); err != nil {
+			return nil, fmt.Errorf("upload %s: %w", path, err)
+		}
+		token, _, _ := strings.Cut(object.DownloadTokens, ",")
+		urls[s.objectURL(path, "")] = s.objectURL(path, token)
+	}
+	return urls, nil
+}
+
+// rewriteImageURLs replaces every image reference in the data with its
+// uploaded URL.
+func rewriteImageURLs(data *MockData, urls map[string]string) {
+	replace := func(images []Image) {
+		for i := range images {
+			if uploaded, ok := urls[images[i].URL]; ok {
+				images[i].URL = uploaded
+			}
+		}
+	}
+	replaceProducts := func(products map[string]FirebaseProductData) {
+		for _, product := range products {
+			replace(product.Images)
+			replace(product.ImagesDone)
+		}
+	}
+
+	for _, order := range data.Xoxo.Orders {
+		replaceProducts(order.Products)
+	}
+	for _, claim := range data.Xoxo.WarrantyClaims {
+		replaceProducts(claim.Products)
+	}
+	for id, material := range data.Xoxo.Materials {
+		if uploaded, ok := urls[material.Image]; ok {
+			material.Image = uploaded
+			data.Xoxo.Materials[id] = material
+		}
+	}
+	for _, item := range data.Xoxo.OperationalWorkflowItems {
+		for i, imageURL := range item.Images {
+			if uploaded, ok := urls[imageURL]; ok {
+				item.Images[i] = uploaded
+			}
+		}
+	}
+}
+
+// renderPlaceholder draws a pastel background picked from the path, a band
+// with the stage title, and the asset's labels.
+func renderPlaceholder(asset ImageAsset) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, placeholderWidth, placeholderHeight))
+
+	hash := fnv.New32a()
+	hash.Write([]byte(asset.Path))
+	sum := hash.Sum32()
+	background := color.RGBA{uint8(0xc0 + sum&0x3f), uint8(0xc0 + sum>>8&0x3f), uint8(0xc0 + sum>>16&0x3f), 0xff}
+	fillRect(img, img.Bounds(), background)
+
+	stage, ok := placeholderStages[asset.Stage]
+	if !ok {
+		stage.Title = strings.ToUpper(asset.Stage)
+		stage.Band = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	}
+	bandHeight := 7*titleScale + 48
+	fillRect(img, image.Rect(0, 0, placeholderWidth, bandHeight), stage.Band)
+	drawLabel(img, stage.Title, 24, 24, titleScale, color.White)
+
+	y := bandHeight + 32
+	for _, label := range asset.Labels {
+		for _, line := range wrapLabel(label, (placeholderWidth-48)/(6*labelScale)) {
+			drawLabel(img, line, 24, y, labelScale, color.RGBA{0x1a, 0x20, 0x2c, 0xff})
+			y += 10 * labelScale
+		}
+	}
+	return img
+}
+
+// wrapLabel breaks text into lines of at most width characters, between words
+// where it can.
+func wrapLabel(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		for len([]rune(word)) > width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			lines = append(lines, string([]rune(word)[:width]))
+			word = string([]rune(word)[width:])
+		}
+		switch {
+		case line == "":
+			line = word
+		case len([]rune(line))+1+len([]rune(word)) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func fillRect(img *image.RGBA, rect image.Rectangle, c color.Color) {
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			img.Set(x, y, c)
+		}
+	}
+}
+
+// drawLabel writes a line of text in placeholderFont, folded to unaccented
+// capitals and cut off at the right edge.
+func drawLabel(img *image.RGBA, text string, x, y, scale int, c color.Color) {
+	for _, r := range strings.ToUpper(text) {
+		if x+5*scale > placeholderWidth-24 {
+			return
+		}
+		if folded, ok := vietnameseAccents[r]; ok {
+			r = folded
+		}
+		glyph, ok := placeholderFont[r]
+		if !ok {
+			if unicode.IsSpace(r) {
+				x += 6 * scale
+				continue
+			}
+			glyph = placeholderFont['?']
+		}
+		for row, bits := range glyph {
+			for col := 0; col < 5; col++ {
+				if bits&(0x10>>col) != 0 {
+					fillRect(img, image.Rect(x+col*scale, y+row*scale, x+(col+1)*scale, y+(row+1)*scale), c)
+				}
+			}
+		}
+		x += 6 * scale
+	}
+}
+
+// placeholderFont is a 5x7 bitmap font, one row per byte with the leftmost
+// pixel in bit 4.
+var placeholderFont = map[rune][7]uint8{
+	'0': {0x0E, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0E},
+	'1': {0x04, 0x0C, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'2': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1F},
+	'3': {0x1F, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0E},
+	'4': {0x02, 0x06, 0x0A, 0x12, 0x1F, 0x02, 0x02},
+	'5': {0x1F, 0x10, 0x1E, 0x01, 0x01, 0x11, 0x0E},
+	'6': {0x06, 0x08, 0x10, 0x1E, 0x11, 0x11, 0x0E},
+	'7': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8': {0x0E, 0x11, 0x11, 0x0E, 0x11, 0x11, 0x0E},
+	'9': {0x0E, 0x11, 0x11, 0x0F, 0x01, 0x02, 0x0C},
+	'A': {0x0E, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'B': {0x1E, 0x11, 0x11, 0x1E, 0x11, 0x11, 0x1E},
+	'C': {0x0E, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0E},
+	'D': {0x1C, 0x12, 0x11, 0x11, 0x11, 0x12, 0x1C},
+	'E': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x1F},
+	'F': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x10},
+	'G': {0x0E, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0F},
+	'H': {0x11, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'I': {0x0E, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'J': {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0C},
+	'K': {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
+	'L': {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1F},
+	'M': {0x11, 0x1B, 0x15, 0x15, 0x11, 0x11, 0x11},
+	'N': {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+	'O': {0x0E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'P': {0x1E, 0x11, 0x11, 0x1E, 0x10, 0x10, 0x10},
+	'Q': {0x0E, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0D},
+	'R': {0x1E, 0x11, 0x11, 0x1E, 0x14, 0x12, 0x11},
+	'S': {0x0F, 0x10, 0x10, 0x0E, 0x01, 0x01, 0x1E},
+	'T': {0x1F, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'U': {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'V': {0x11, 0x11, 0x11, 0x11, 0x11, 0x0A, 0x04},
+	'W': {0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0A},
+	'X': {0x11, 0x11, 0x0A, 0x04, 0x0A, 0x11, 0x11},
+	'Y': {0x11, 0x11, 0x11, 0x0A, 0x04, 0x04, 0x04},
+	'Z': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1F},
+	'-': {0x00, 0x00, 0x00, 0x1F, 0x00, 0x00, 0x00},
+	'_': {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1F},
+	'/': {0x00, 0x01, 0x02, 0x04, 0x08, 0x10, 0x00},
+	'.': {0x00, 0x00, 0x00, 0x00, 0x00, 0x0C, 0x0C},
+	',': {0x00, 0x00, 0x00, 0x00, 0x0C, 0x04, 0x08},
+	':': {0x00, 0x0C, 0x0C, 0x00, 0x0C, 0x0C, 0x00},
+	'#': {0x0A, 0x0A, 0x1F, 0x0A, 0x1F, 0x0A, 0x0A},
+	'(': {0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02},
+	')': {0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},
+	'+': {0x00, 0x04, 0x04, 0x1F, 0x04, 0x04, 0x00},
+	'&': {0x0C, 0x12, 0x14, 0x08, 0x15, 0x12, 0x0D},
+	'?': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x00, 0x04},
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// openingBalanceReason marks the import that carries a material's stock from
+// before the history window. It is not a purchase, so it has no finance entry.
+const openingBalanceReason = "Tồn đầu kỳ"
+
+var exportReasons = []string{"Xử lý đơn hàng", "Bán lẻ cho khách", "Kiểm tra", "Hư hỏng"}
+
+// StockPlan is the state a material should end in after its ledger is replayed.
+type StockPlan struct {
+	TargetStock int
+	// LastMovementAt is when the material last moved; materials in the
+	// long_stock scenario have had no movement for a while.
+	LastMovementAt int64
+}
+
+// buildInventoryLedger generates numMovements imports and exports over the
+// history window and closes each material's ledger with an export at its
+// LastMovementAt that lands on the planned stock. The opening balance is
+// solved backwards so that stock never goes negative on the way. Codes are
+// assigned in date order.
+func buildInventoryLedger(materials map[string]Material, plans map[string]StockPlan, numMovements int, timeline *Timeline) map[string]InventoryTransaction {
+	materialIDs := make([]string, 0, len(materials))
+	for materialID := range materials {
+		materialIDs = append(materialIDs, materialID)
+	}
+	sort.Strings(materialIDs)
+
+	movements := make(map[string][]InventoryTransaction)
+	for i := 0; i < numMovements && len(materialIDs) > 0; i++ {
+		materialID := materialIDs[rand.Intn(len(materialIDs))]
+		// A purchase covers about three jobs' worth of exports
+		txnType := "import"
+		if rand.Float32() < 0.75 {
+			txnType = "export"
+		}
+		material := materials[materialID]
+		at := timeline.Between(material.CreatedAt, plans[materialID].LastMovementAt)
+		movements[materialID] = append(movements[materialID], newInventoryTransaction(material, txnType, movementQuantity(material, txnType), at))
+	}
+
+	var ledger []InventoryTransaction
+	for _, materialID := range materialIDs {
+		material := materials[materialID]
+		plan := plans[materialID]
+		moves := movements[materialID]
+		sort.Slice(moves, func(a, b int) bool { return moves[a].CreatedAt < moves[b].CreatedAt })
+
+		net, lowest := 0, 0
+		for _, txn := range moves {
+			net += signedQuantity(txn)
+			lowest = min(lowest, net)
+		}
+		closing := movementQuantity(material, "export")
+		opening := max(plan.TargetStock+closing-net, -lowest)
+		if opening > 0 {
+			txn := newInventoryTransaction(material, "import", opening, material.CreatedAt)
+			txn.Reason = openingBalanceReason
+			txn.Note = fmt.Sprintf("Tồn đầu kỳ %s", material.Name)
+			ledger = append(ledger, txn)
+		}
+		ledger = append(ledger, moves...)
+
+		ledger = append(ledger, newInventoryTransaction(material, "export", opening+net-plan.TargetStock, plan.LastMovementAt))
+	}
+
+	sort.SliceStable(ledger, func(a, b int) bool { return ledger[a].CreatedAt < ledger[b].CreatedAt })
+	transactions := make(map[string]InventoryTransaction, len(ledger))
+	for i, txn := range ledger {
+		txn.Code = generateTransactionCode(i)
+		transactions[txn.Code] = txn
+	}
+	return transactions
+}
+
+// movementQuantity sizes a movement in the material's unit: an import around
+// the material's typical purchase, an export a tenth to a half of one.
+func movementQuantity(material Material, txnType string) int {
+	typical := max(1, materialImports[material.Name])
+	if txnType == "export" {
+		return max(1, typical*(10+rand.Intn(41))/100)
+	}
+	return max(1, typical*(70+rand.Intn(61))/100)
+}
+
+func newInventoryTransaction(material Material, txnType string, quantity int, at int64) InventoryTransaction {
+	date := time.UnixMilli(at).In(vietnamTime)
+	txn := InventoryTransaction{
+		MaterialID:   material.ID,
+		MaterialName: material.Name,
+		Type:         txnType,
+		Quantity:     quantity,
+		Unit:         material.Unit,
+		Price:        material.ImportPrice,
+		TotalAmount:  quantity * material.ImportPrice,
+		Date:         date.Format("2006-01-02"),
+		Warehouse:    material.Warehouse,
+		Supplier:     material.Supplier,
+		Note:         fmt.Sprintf("Giao dịch %s cho %s", txnType, material.Name),
+		CreatedAt:    at,
+	}
+	if txnType == "export" {
+		txn.Reason = exportReasons[rand.Intn(len(exportReasons))]
+	}
+	return txn
+}
+
+func signedQuantity(txn InventoryTransaction) int {
+	if txn.Type == "export" {
+		return -txn.Quantity
+	}
+	return txn.Quantity
+}
+
+// replayInventoryLedger recomputes each material's stock from its transactions
+// in date order, the way InventoryService applies them one at a time, and
+// writes the balance and the date of the last movement back to the material.
+// An export larger than the stock on hand is cut down to what is available.
+func replayInventoryLedger(materials map[string]Material, transactions map[string]InventoryTransaction) {
+	codes := make([]string, 0, len(transactions))
+	for code := range transactions {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(a, b int) bool {
+		ta, tb := transactions[codes[a]], transactions[codes[b]]
+		if ta.CreatedAt != tb.CreatedAt {
+			return ta.CreatedAt < tb.CreatedAt
+		}
+		return codes[a] < codes[b]
+	})
+
+	stock := make(map[string]int, len(materials))
+	peak := make(map[string]int, len(materials))
+	for _, code := range codes {
+		txn := transactions[code]
+		material, ok := materials[txn.MaterialID]
+		if !ok {
+			continue
+		}
+		if txn.Type == "export" && txn.Quantity > stock[txn.MaterialID] {
+			txn.Quantity = stock[txn.MaterialID]
+			txn.TotalAmount = txn.Quantity * txn.Price
+			transactions[code] = txn
+		}
+		stock[txn.MaterialID] += signedQuantity(txn)
+		peak[txn.MaterialID] = max(peak[txn.MaterialID], stock[txn.MaterialID])
+
+		material.LastUpdated = txn.Date
+		material.UpdatedAt = txn.CreatedAt
+		materials[txn.MaterialID] = material
+	}
+
+	for materialID, material := range materials {
+		material.StockQuantity = stock[materialID]
+		material.MaxCapacity = max(material.MaxCapacity, peak[materialID])
+		materials[materialID] = material
+	}
+}
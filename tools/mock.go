@@ -1,44 +1,176 @@
+// Mock data generator for the xoxo Realtime Database tree.
+//
+// Run from the repository root, leaving out the tests (go test ./tools/*.go
+// runs them):
+//
+//	go run $(ls tools/*.go | grep -v _test) [-vocab default|path/to/pack.json] [-days 365] [-lapsed-customers 0.3] [-duplicate-phones 0.3] [-opening-cash 4000000000] [-finance-report report.json] [output.json]
+//
+// or generate and write straight into the emulators started with
+// `firebase emulators:start --only auth,database,storage`, creating a login
+// account for every member and uploading a placeholder image, labelled with
+// its order, product and stage, for every image reference:
+//
+//	go run $(ls tools/*.go | grep -v _test) seed [generation flags] [-project demo-xoxo] [-database-url http://127.0.0.1:9000] [-ns demo-xoxo-default-rtdb] [-auth secret] [-chunk-size 1048576] [-retries 3] [-allow-remote]
+//	    [-accounts=false] [-auth-emulator-host 127.0.0.1:9099] [-password 123456] [-role-password admin=secret]
+//	    [-images=false] [-storage-emulator-host 127.0.0.1:9199] [-bucket demo-xoxo.appspot.com]
+//
+// Seed deletes the whole tree before writing it. It and every other command
+// that talks to a database refuse any but a local emulator (localhost, a
+// loopback address or $FIREBASE_DATABASE_EMULATOR_HOST) unless -allow-remote
+// is given.
+//
+// The append command tops up an existing dataset instead of replacing it:
+// it generates more orders, with their workflows, images and payments, the
+// finance income entries of those payments and the customers who placed
+// them, continuing the code sequences of what is there. It adds nothing
+// else: no refunds, feedback, warranty claims, technician tasks, stock
+// movements, supplier payments or payroll. A file is updated in place;
+// without one the records are written to the database in a single
+// multi-path update, so nothing created by hand is lost:
+//
+//	go run $(ls tools/*.go | grep -v _test) append [-vocab default|path/to/pack.json] [-days 365] [-duplicate-phones 0.3] [-orders 10] [-returning-customers 0.3] [-dry-run]
+//	    [-project demo-xoxo] [-database-url http://127.0.0.1:9000] [-ns demo-xoxo-default-rtdb] [-auth secret] [-retries 3] [-allow-remote]
+//	    [-images=false] [-storage-emulator-host 127.0.0.1:9199] [-bucket demo-xoxo.appspot.com] [mock-data.json]
+//
+// Images in a generated file point at the Storage emulator's
+// demo-xoxo.appspot.com bucket, which only the seed and append commands fill.
+//
+// The export command reads the tree, or some paths in it, back from a
+// database into a file in the same layout, paging through big collections:
+//
+//	go run $(ls tools/*.go | grep -v _test) export [-project demo-xoxo] [-database-url http://127.0.0.1:9000] [-ns demo-xoxo-default-rtdb] [-auth secret] [-retries 3] [-allow-remote]
+//	    [-path orders] [-path members/ADMIN_FIXED_001] [-page-size 500] [export.json]
+//
+// The serve command serves a generated or exported file from memory with the
+// Realtime Database REST API, including orderBy/startAt/endAt/equalTo/limitTo
+// queries, shallow reads and text/event-stream listeners, so scripts and CI
+// jobs that use the REST API, like the commands here, need no emulator.
+// It also speaks the WebSocket protocol of the client SDK behind useRealtime
+// and useRealtimeList (listens, with or without a query, gets, sets, updates,
+// transactions and onDisconnect), so the app runs against it with
+// NEXT_PUBLIC_FIREBASE_DATABASE_URL=http://127.0.0.1:9000?ns=demo-xoxo-default-rtdb.
+// Writes are kept in memory only. Neither side enforces security rules: any
+// credential is accepted and every read and write allowed, so test rules with
+// test-rules or the emulator. Sign-in still goes through Firebase Auth.
+//
+//	go run $(ls tools/*.go | grep -v _test) serve [-addr 127.0.0.1:9000] [mock-data.json]
+//
+// The test-rules command evaluates security rules against a dataset: every
+// case in the table (who, which operation, where, with what data) must be
+// allowed or denied as it says:
+//
+//	go run $(ls tools/*.go | grep -v _test) test-rules [-rules firebase.rule.generated.json] [-cases firebase.rule.test.json] [-members xoxo/members] [mock-data.json]
+//
+// firebase.rule.generated.json is written by the rules command from the
+// per-collection, per-role matrix in firebase.rule.spec.json:
+//
+//	go run $(ls tools/*.go | grep -v _test) rules [-spec firebase.rule.spec.json] [firebase.rule.generated.json]
+//
+// The generated rules check the role custom claim, which accounts created
+// before api/members/create set it do not carry. They do not replace the
+// deployed firebase.rule.json until every account has its claim, which
+// POST api/admin/backfill-role-claims, called with an admin's ID token, sets
+// from the members' roles.
+//
+// The diff command compares two datasets, such as yesterday's export and
+// today's or the output of two generator versions: the records added,
+// removed and changed in every collection, matched on their code, with the
+// fields that changed, then how order value, payments, the ledger and the
+// stock of every material moved:
+//
+//	go run $(ls tools/*.go | grep -v _test) diff [-root xoxo] [-by-key] [-ignore updatedAt] [-collection orders] [-summary] before.json after.json
+//
+// The purge command does what the admin deletion pages do, from the whole tree
+// down to some orders, on a file in place or on the database in one
+// multi-path update. Records are picked by collection, date and code prefix;
+// those referencing them, such as the refunds, feedback and finance entries
+// of an order, go with them. Customers losing orders get their updatedAt and
+// customerGroup worked out again from the orders left, and records naming a
+// removed one only in free text, such as the supplier payment of a goods
+// receipt, are listed as left dangling. Removing whole collections needs -yes:
+//
+//	go run $(ls tools/*.go | grep -v _test) purge [-collection orders] [-from 2025-01-01] [-to 2025-06-30] [-prefix ORD2025] [-dependents=false] [-dry-run] [-yes] [-all]
+//	    [-project demo-xoxo] [-database-url http://127.0.0.1:9000] [-ns demo-xoxo-default-rtdb] [-auth secret] [-retries 3] [-allow-remote] [mock-data.json]
 package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
+	"math"
 	"math/rand"
+	"net/url"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // Configuration for mock data generation
 type MockConfig struct {
-	NumDepartments    int
-	NumSalesMembers   int
-	NumAdminMembers   int
-	NumDevMembers     int
-	NumWorkersPerDept int
-	NumOrders         int
-	NumWarrantyClaims int
-	NumMaterials      int
-	NumCategories     int
-	NumInventoryTxns  int
-	NumFinanceTxns    int
-	NumRefunds        int
-	NumFeedbacks      int
+	NumDepartments       int
+	NumSalesMembers      int
+	NumAdminMembers      int
+	NumDevMembers        int
+	NumWorkersPerDept    int
+	NumOrders            int
+	NumWarrantyClaims    int
+	NumMaterials         int
+	NumCategories        int
+	NumManualFinanceTxns int
+	NumRefunds           int
+	NumFeedbacks         int
+
+	NumOperationalWorkflowItems int
+	NumStandaloneTasks          int
+
+	// InventoryTxnsPerOrder sizes the stock ledger, and with it purchasing,
+	// to the orders that use up the stock.
+	InventoryTxnsPerOrder float64
+
+	// HistoryDays is how far back business events go. Timestamps within the
+	// window follow defaultTimeModel (see timeline.go).
+	HistoryDays int
+
+	// OpeningCashBalance is the cash on hand at the start of the history.
+	// Generation fails if the balance would drop below zero on some day.
+	// The ledger books the staff's full salaries, while the orders are only
+	// a sample of the work, so the default covers a year of payroll for the
+	// default staff; raise it with the history or the staff.
+	OpeningCashBalance int
+
+	// LapsedCustomerRate is the share of customers, among those whose first
+	// order is old enough, who stop coming back (see customers.go).
+	LapsedCustomerRate float64
+
+	// DuplicatePhoneRate is the share of customers given a phone number that
+	// already belongs to someone else. Zero keeps every phone unique.
+	DuplicatePhoneRate float64
 }
 
 var defaultConfig = MockConfig{
-	NumDepartments:    5,
-	NumSalesMembers:   5,
-	NumAdminMembers:   2,
-	NumDevMembers:     2,
-	NumWorkersPerDept: 3,
-	NumOrders:         20,
-	NumWarrantyClaims: 5,
-	NumMaterials:      15,
-	NumCategories:     5,
-	NumInventoryTxns:  30,
-	NumFinanceTxns:    25,
-	NumRefunds:        3,
-	NumFeedbacks:      10,
+	NumDepartments:       5,
+	NumSalesMembers:      5,
+	NumAdminMembers:      2,
+	NumDevMembers:        2,
+	NumWorkersPerDept:    3,
+	NumOrders:            20,
+	NumWarrantyClaims:    5,
+	NumMaterials:         15,
+	NumCategories:        5,
+	NumManualFinanceTxns: 12,
+	NumRefunds:           3,
+	NumFeedbacks:         10,
+
+	NumOperationalWorkflowItems: 20,
+	NumStandaloneTasks:          10,
+
+	InventoryTxnsPerOrder: 1.0,
+
+	HistoryDays:        365,
+	OpeningCashBalance: 4000000000,
+	LapsedCustomerRate: 0.3,
 }
 
 // Data structures matching TypeScript interfaces
@@ -59,9 +191,27 @@ type Member struct {
 	Role        string   `json:"role"`
 	Departments []string `json:"departments,omitempty"`
 	DateOfBirth string   `json:"date_of_birth"`
-	IsActive    bool     `json:"isActive,omitempty"`
-	CreatedAt   int64    `json:"createdAt,omitempty"`
-	UpdatedAt   int64    `json:"updatedAt,omitempty"`
+	IsActive    bool     `json:"isActive"`
+
+	Avatar          string   `json:"avatar,omitempty"`
+	IDCard          string   `json:"idCard,omitempty"`
+	Gender          string   `json:"gender,omitempty"`
+	Province        string   `json:"province,omitempty"`
+	Ward            string   `json:"ward,omitempty"`
+	Address         string   `json:"address,omitempty"`
+	TimesheetCode   string   `json:"timesheetCode,omitempty"`
+	Debt            int      `json:"debt,omitempty"`
+	PayrollBranch   string   `json:"payrollBranch,omitempty"`
+	WorkingBranches []string `json:"workingBranches,omitempty"`
+	Position        string   `json:"position,omitempty"`
+	StartDate       string   `json:"startDate,omitempty"`
+	LoginAccount    string   `json:"loginAccount,omitempty"`
+	SalaryType      string   `json:"salaryType,omitempty"`
+	SalaryAmount    int      `json:"salaryAmount,omitempty"`
+	Notes           string   `json:"notes,omitempty"`
+
+	CreatedAt int64 `json:"createdAt,omitempty"`
+	UpdatedAt int64 `json:"updatedAt,omitempty"`
 }
 
 type Workflow struct {
@@ -124,6 +274,45 @@ type FirebaseOrderData struct {
 	IsDepositPaid  bool                           `json:"isDepositPaid,omitempty"`
 	CustomerCode   string                         `json:"customerCode,omitempty"`
 	Issues         []string                       `json:"issues,omitempty"`
+
+	Payments        []PaymentInfo `json:"payments,omitempty"`
+	TotalPaidAmount int           `json:"totalPaidAmount,omitempty"`
+	RemainingDebt   int           `json:"remainingDebt,omitempty"`
+}
+
+type PaymentInfo struct {
+	ID         string `json:"id"`
+	Amount     int    `json:"amount"`
+	Content    string `json:"content,omitempty"`
+	PaidAt     int64  `json:"paidAt"`
+	PaidBy     string `json:"paidBy,omitempty"`
+	PaidByName string `json:"paidByName,omitempty"`
+	CreatedAt  int64  `json:"createdAt,omitempty"`
+}
+
+// Customer province, district and ward hold provinces.open-api.vn codes, as CustomerFormModal stores them.
+type Customer struct {
+	Code           string `json:"code"`
+	Name           string `json:"name"`
+	Phone          string `json:"phone"`
+	Email          string `json:"email,omitempty"`
+	Address        string `json:"address"`
+	CustomerSource string `json:"customerSource"`
+	Province       int    `json:"province,omitempty"`
+	District       int    `json:"district,omitempty"`
+	Ward           int    `json:"ward,omitempty"`
+	CustomerType   string `json:"customerType,omitempty"`
+	Gender         string `json:"gender,omitempty"`
+	CustomerGroup  string `json:"customerGroup,omitempty"`
+	CreatedAt      int64  `json:"createdAt"`
+	UpdatedAt      int64  `json:"updatedAt"`
+}
+
+type CustomerGroup struct {
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	CreatedAt int64  `json:"createdAt"`
+	UpdatedAt int64  `json:"updatedAt"`
 }
 
 type WarrantyClaim struct {
@@ -166,9 +355,13 @@ type Material struct {
 	Unit               string `json:"unit"`
 	MinThreshold       int    `json:"minThreshold"`
 	MaxCapacity        int    `json:"maxCapacity"`
+	AlertThreshold     int    `json:"alertThreshold,omitempty"`
+	ExpiryDate         string `json:"expiryDate,omitempty"`
+	Warehouse          string `json:"warehouse,omitempty"`
 	Supplier           string `json:"supplier,omitempty"`
-	ImportPrice        int    `json:"importPrice,omitempty"`
 	LastUpdated        string `json:"lastUpdated,omitempty"`
+	Image              string `json:"image,omitempty"`
+	ImportPrice        int    `json:"importPrice,omitempty"`
 	LongStockAlertDays int    `json:"longStockAlertDays,omitempty"`
 	CreatedAt          int64  `json:"createdAt,omitempty"`
 	UpdatedAt          int64  `json:"updatedAt,omitempty"`
@@ -184,6 +377,7 @@ type InventoryTransaction struct {
 	Price        int    `json:"price,omitempty"`
 	TotalAmount  int    `json:"totalAmount,omitempty"`
 	Date         string `json:"date"`
+	Warehouse    string `json:"warehouse,omitempty"`
 	Supplier     string `json:"supplier,omitempty"`
 	Reason       string `json:"reason,omitempty"`
 	Note         string `json:"note,omitempty"`
@@ -191,6 +385,39 @@ type InventoryTransaction struct {
 	CreatedAt    int64  `json:"createdAt"`
 }
 
+type Supplier struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Phone       string `json:"phone,omitempty"`
+	Address     string `json:"address,omitempty"`
+	BankAccount string `json:"bankAccount,omitempty"`
+	BankName    string `json:"bankName,omitempty"`
+	Status      string `json:"status,omitempty"`
+	CreatedAt   int64  `json:"createdAt"`
+	UpdatedAt   int64  `json:"updatedAt"`
+}
+
+type SupplierPayment struct {
+	Code          string `json:"code"`
+	SupplierID    string `json:"supplierId"`
+	SupplierName  string `json:"supplierName"`
+	Amount        int    `json:"amount"`
+	PaymentDate   int64  `json:"paymentDate"`
+	PaymentMethod string `json:"paymentMethod,omitempty"`
+	BankAccount   string `json:"bankAccount,omitempty"`
+	BankName      string `json:"bankName,omitempty"`
+	Notes         string `json:"notes,omitempty"`
+	CreatedBy     string `json:"createdBy"`
+	CreatedByName string `json:"createdByName,omitempty"`
+	CreatedAt     int64  `json:"createdAt"`
+	UpdatedAt     int64  `json:"updatedAt"`
+}
+
+type InventorySettings struct {
+	DefaultLongStockDays int   `json:"defaultLongStockDays"`
+	UpdatedAt            int64 `json:"updatedAt,omitempty"`
+}
+
 type FinanceTransaction struct {
 	ID            string `json:"id"`
 	Date          int64  `json:"date"`
@@ -252,127 +479,250 @@ type CustomerFeedback struct {
 	UpdatedAt       int64  `json:"updatedAt"`
 }
 
+type OperationalWorkflowTask struct {
+	ID        string `json:"id"`
+	TaskName  string `json:"taskName"`
+	TaskOrder int    `json:"taskOrder"`
+}
+
+type OperationalWorkflowMaterial struct {
+	MaterialID   string `json:"materialId"`
+	MaterialName string `json:"materialName"`
+	Quantity     int    `json:"quantity"`
+	Unit         string `json:"unit"`
+}
+
+type OperationalWorkflowJob struct {
+	ID        string                        `json:"id"`
+	JobName   string                        `json:"jobName"`
+	JobOrder  int                           `json:"jobOrder"`
+	Tasks     []OperationalWorkflowTask     `json:"tasks"`
+	Materials []OperationalWorkflowMaterial `json:"materials,omitempty"`
+}
+
+type OperationalWorkflow struct {
+	WorkflowName   string                        `json:"workflowName"`
+	DepartmentCode string                        `json:"departmentCode,omitempty"`
+	Jobs           []OperationalWorkflowJob      `json:"jobs"`
+	Materials      []OperationalWorkflowMaterial `json:"materials,omitempty"`
+	CreatedAt      int64                         `json:"createdAt"`
+	UpdatedAt      int64                         `json:"updatedAt"`
+}
+
+type OperationalWorkflowItem struct {
+	WorkflowID         string   `json:"workflowId"`
+	WorkflowName       string   `json:"workflowName"`
+	JobID              string   `json:"jobId"`
+	JobName            string   `json:"jobName"`
+	JobOrder           int      `json:"jobOrder"`
+	Status             string   `json:"status"`
+	CreatedAt          int64    `json:"createdAt"`
+	UpdatedAt          int64    `json:"updatedAt"`
+	StartedAt          int64    `json:"startedAt,omitempty"`
+	CompletedAt        int64    `json:"completedAt,omitempty"`
+	DurationHours      float64  `json:"durationHours,omitempty"`
+	CancelledAt        int64    `json:"cancelledAt,omitempty"`
+	CancelledBy        string   `json:"cancelledBy,omitempty"`
+	CancelledByName    string   `json:"cancelledByName,omitempty"`
+	CancelReason       string   `json:"cancelReason,omitempty"`
+	ConfirmedCancelled bool     `json:"confirmedCancelled,omitempty"`
+	AssignedTo         string   `json:"assignedTo,omitempty"`
+	AssignedToName     string   `json:"assignedToName,omitempty"`
+	OrderCode          string   `json:"orderCode,omitempty"`
+	ProductID          string   `json:"productId,omitempty"`
+	ProductName        string   `json:"productName,omitempty"`
+	CustomerName       string   `json:"customerName,omitempty"`
+	Notes              string   `json:"notes,omitempty"`
+	Images             []string `json:"images,omitempty"`
+}
+
+type StandaloneTask struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Assignee    string `json:"assignee"`
+	Deadline    int64  `json:"deadline,omitempty"`
+	CreatedAt   int64  `json:"createdAt"`
+	CreatedBy   string `json:"createdBy"`
+	Status      string `json:"status"`
+	IsDone      bool   `json:"isDone"`
+	Type        string `json:"type"`
+}
+
 type MockData struct {
 	Xoxo struct {
 		Departments           map[string]Department           `json:"departments"`
 		Members               map[string]Member               `json:"members"`
 		Workflows             map[string]Workflow             `json:"workflows"`
+		Customers             map[string]Customer             `json:"customers"`
+		CustomerGroups        map[string]CustomerGroup        `json:"customerGroups"`
 		Orders                map[string]FirebaseOrderData    `json:"orders"`
 		WarrantyClaims        map[string]WarrantyClaim        `json:"warrantyClaims"`
 		Categories            map[string]Category             `json:"categories"`
 		Materials             map[string]Material             `json:"materials"`
 		InventoryTransactions map[string]InventoryTransaction `json:"inventoryTransactions"`
-		FinanceTransactions   map[string]FinanceTransaction   `json:"financeTransactions"`
-		Refunds               map[string]RefundRequest        `json:"refunds"`
-		Feedbacks             map[string]CustomerFeedback     `json:"feedbacks"`
+		Inventory             struct {
+			Settings InventorySettings `json:"settings"`
+		} `json:"inventory"`
+		Suppliers        map[string]Supplier        `json:"suppliers"`
+		SupplierPayments map[string]SupplierPayment `json:"supplier_payments"`
+		Finance          struct {
+			Transactions map[string]FinanceTransaction `json:"transactions"`
+		} `json:"finance"`
+		Refunds   map[string]RefundRequest    `json:"refunds"`
+		Feedbacks map[string]CustomerFeedback `json:"feedbacks"`
+
+		OperationalWorkflows     map[string]OperationalWorkflow     `json:"operational_workflows"`
+		OperationalWorkflowItems map[string]OperationalWorkflowItem `json:"operational_workflow_items"`
+		StandaloneTasks          map[string]StandaloneTask          `json:"standalone_tasks"`
 	} `json:"xoxo"`
 }
 
 var (
-	firstNames  = []string{"Nguyễn", "Trần", "Lê", "Phạm", "Hoàng", "Võ", "Đặng", "Bùi", "Đỗ", "Hồ"}
-	lastNames   = []string{"Văn", "Thị", "Minh", "Hồng", "Lan", "Anh", "Dũng", "Hùng", "Thảo", "Linh"}
-	middleNames = []string{"Anh", "Minh", "Thị", "Văn", "Hồng", "Lan", "Dũng", "Hùng", "Thảo", "Linh"}
-
-	departments = []struct {
-		Code string
-		Name string
-	}{
-		{"DEPT_001", "Phòng Cắt"},
-		{"DEPT_002", "Phòng May"},
-		{"DEPT_003", "Phòng Là Ủi"},
-		{"DEPT_004", "Phòng Kiểm Tra"},
-		{"DEPT_005", "Phòng Đóng Gói"},
-	}
-
-	workflowNames = map[string][]string{
-		"DEPT_001": {"Cắt vải", "Cắt chỉ", "Cắt phụ liệu"},
-		"DEPT_002": {"May thân", "May tay áo", "May cổ áo", "May đường viền"},
-		"DEPT_003": {"Là ủi thân", "Là ủi tay áo", "Là ủi hoàn thiện"},
-		"DEPT_004": {"Kiểm tra chất lượng", "Kiểm tra kích thước", "Kiểm tra đường may"},
-		"DEPT_005": {"Đóng gói", "Dán nhãn", "Bọc bảo vệ"},
-	}
-
-	productNames = []string{
-		"Áo thun nữ size M",
-		"Áo sơ mi nam size L",
-		"Quần jeans nam size 32",
-		"Váy công sở size S",
-		"Áo khoác nữ size M",
-		"Quần short nam size 30",
-		"Áo len nữ size L",
-		"Quần tây nam size 34",
-	}
-
-	materialNames = []string{
-		"Vải cotton",
-		"Vải denim",
-		"Vải lụa",
-		"Chỉ may",
-		"Khóa kéo",
-		"Khuy áo",
-		"Da bò",
-		"Vải thun",
-		"Bông vải",
-		"Túi vải",
-	}
-
-	categoryNames = []string{
-		"Vải",
-		"Phụ liệu",
-		"Da",
-		"Hóa chất",
-		"Bao bì",
-	}
-
-	supplierNames = []string{
-		"Công ty Vải ABC",
-		"Nhà cung cấp Phụ liệu XYZ",
-		"Công ty Da DEF",
-		"Nhà cung cấp Hóa chất GHI",
-		"Công ty Bao bì JKL",
-	}
+	materialStockScenarios = []string{"normal", "low_stock", "normal", "near_alert", "normal", "long_stock"}
+	expiryScenarios        = []string{"expired", "near_expiry", "valid", "near_expiry", "valid"}
+
+	genders = []string{"male", "female"}
 
 	// Enum values from enum.ts
 	customerSources  = []string{"facebook", "zalo", "instagram", "tiktok", "website", "referral", "walk_in", "phone", "other"}
 	roles            = []string{"sales", "worker", "admin", "development"}
-	orderStatuses    = []string{"pending", "confirmed", "in_progress", "on_hold", "completed", "cancelled"}
 	warrantyStatuses = []string{"pending", "confirmed", "in_progress", "on_hold", "completed", "cancelled"}
 	refundStatuses   = []string{"pending", "approved", "rejected", "processed", "cancelled"}
 	refundTypes      = []string{"full", "partial", "compensation"}
 	discountTypes    = []string{"amount", "percentage"}
 	units            = []string{"cai", "hop", "thung", "cuon", "bo", "kg", "g", "mg", "tan", "lit", "ml", "m3", "m", "cm", "mm", "m2", "cm2", "tam", "bao", "palette"}
 	feedbackTypes    = []string{"Khen", "Chê", "Bức xúc", "Góp ý"}
-	categoryColors   = []string{"#1890ff", "#52c41a", "#faad14", "#f5222d", "#722ed1", "#eb2f96", "#13c2c2"}
+
+	// Finance values from financeService.ts and SupplierPayment in inventory.ts
+	financeTypes      = []string{"income", "expense"}
+	financeCategories = []string{"inventory", "order", "salary"}
+	paymentMethods    = []string{"cash", "bank_transfer"}
+
+	// Values used by the technician screens (operationalWorkflowService.ts, task-assignment/page.tsx)
+	operationalItemStatuses = []string{"pending", "completed", "cancelled"}
+	standaloneTaskStatuses  = []string{"pending", "in_progress", "completed"}
+	standaloneTaskTypes     = []string{"other", "strategy_1", "strategy_2", "strategy_3", "strategy_4", "strategy_5", "strategy_6", "strategy_7"}
 )
 
-func randomName() string {
-	firstName := firstNames[rand.Intn(len(firstNames))]
-	middleName := middleNames[rand.Intn(len(middleNames))]
-	lastName := lastNames[rand.Intn(len(lastNames))]
-	return fmt.Sprintf("%s %s %s", firstName, middleName, lastName)
+func randomGender() string {
+	return genders[rand.Intn(len(genders))]
 }
 
-func randomPhone() string {
-	return fmt.Sprintf("09%08d", rand.Intn(100000000))
+// randomName builds "<surname> <middle name> <given name>" for the given
+// gender, so the name never contradicts the stored gender field.
+func randomName(gender string) string {
+	surname := pickWeightedName(personNames.Surnames)
+	middleName := pickWeightedName(personNames.MiddleNames[gender])
+	givenName := pickWeightedName(personNames.GivenNames[gender])
+	// Avoid names like "Nguyễn Minh Minh"
+	for givenName == middleName {
+		givenName = pickWeightedName(personNames.GivenNames[gender])
+	}
+	return fmt.Sprintf("%s %s %s", surname, middleName, givenName)
 }
 
-func randomEmail(name string) string {
-	cleanName := ""
-	for _, r := range name {
-		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
-			cleanName += string(r)
+func pickWeightedName(names []WeightedName) string {
+	total := 0
+	for _, name := range names {
+		total += name.Weight
+	}
+	pick := rand.Intn(total)
+	for _, name := range names {
+		if pick < name.Weight {
+			return name.Name
 		}
+		pick -= name.Weight
 	}
-	return fmt.Sprintf("%s%d@gmail.com", cleanName, rand.Intn(1000))
+	return names[len(names)-1].Name
 }
 
 func randomDateOfBirth() string {
-	year := 1980 + rand.Intn(30)
+	year := 1980 + rand.Intn(25)
 	month := 1 + rand.Intn(12)
 	day := 1 + rand.Intn(28)
 	return fmt.Sprintf("%04d-%02d-%02d", year, month, day)
 }
 
+// maxHireDaysBefore is how long before the history window the longest-serving
+// members were hired. The departments and categories were set up before that.
+const maxHireDaysBefore = 335
+
+// randomHireTime returns a creation time before the history window that
+// orders are generated in, so members never exist later than the work
+// assigned to them.
+func randomHireTime(timeline *Timeline) int64 {
+	start := timeline.Start()
+	return timeline.Between(start-maxHireDaysBefore*dayMillis, start-dayMillis)
+}
+
+// randomSetupTime returns a time in the month before the first hire, when the
+// shop's departments and categories were created.
+func randomSetupTime(timeline *Timeline) int64 {
+	setupEnd := timeline.Start() - maxHireDaysBefore*dayMillis
+	return timeline.Between(setupEnd-30*dayMillis, setupEnd)
+}
+
+// randomIDCard builds a 12-digit CCCD number: province code, gender/century
+// digit, two-digit birth year and a six-digit serial.
+func randomIDCard(provinceCode, gender, dateOfBirth string, used map[string]bool) string {
+	year := 1990
+	fmt.Sscanf(dateOfBirth, "%d", &year)
+	genderDigit := 0
+	if gender == "female" {
+		genderDigit = 1
+	}
+	if year >= 2000 {
+		genderDigit += 2
+	}
+	for {
+		idCard := fmt.Sprintf("%s%d%02d%06d", provinceCode, genderDigit, year%100, rand.Intn(1000000))
+		if !used[idCard] {
+			used[idCard] = true
+			return idCard
+		}
+	}
+}
+
+func randomLoginAccount() string {
+	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	uid := make([]byte, 28)
+	for i := range uid {
+		uid[i] = charset[rand.Intn(len(charset))]
+	}
+	return string(uid)
+}
+
+// fillMemberProfile completes the HR fields shown by EmployeeManager and StaffManager.
+func fillMemberProfile(member *Member, index int, usedIDCards map[string]bool) {
+	address := addresses.Random()
+	if member.Gender == "" {
+		member.Gender = randomGender()
+	}
+	member.Avatar = fmt.Sprintf("https://ui-avatars.com/api/?name=%s&background=random", url.QueryEscape(member.Name))
+	member.IDCard = randomIDCard(address.IDCardPrefix(), member.Gender, member.DateOfBirth, usedIDCards)
+	member.Province = strconv.Itoa(address.ProvinceCode)
+	member.Ward = strconv.Itoa(address.WardCode)
+	member.Address = address.Full()
+	member.TimesheetCode = fmt.Sprintf("CC%04d", index+1)
+	if rand.Float32() < 0.3 {
+		member.Debt = (1 + rand.Intn(10)) * 500000
+	}
+	member.PayrollBranch = branches[0]
+	member.WorkingBranches = []string{branches[0]}
+	if len(branches) > 1 && rand.Float32() < 0.3 {
+		member.WorkingBranches = append(member.WorkingBranches, branches[1+rand.Intn(len(branches)-1)])
+	}
+	// The Chức danh select on hr/members offers, and filters on, the roles.
+	member.Position = member.Role
+	member.StartDate = time.UnixMilli(member.CreatedAt).In(vietnamTime).Format("2006-01-02")
+	member.LoginAccount = randomLoginAccount()
+	member.SalaryType = "fixed"
+	member.SalaryAmount = randomSalary(member.Role)
+	if member.UpdatedAt == 0 {
+		member.UpdatedAt = member.CreatedAt
+	}
+}
+
 func generateID(prefix string, index int) string {
 	return fmt.Sprintf("%s_%03d", prefix, index+1)
 }
@@ -407,6 +757,7 @@ func generateRefundCode(index int) string {
 func generateMockData(config MockConfig) MockData {
 	rand.Seed(time.Now().UnixNano())
 	now := time.Now().Unix() * 1000
+	timeline := newTimeline(defaultTimeModel, time.UnixMilli(now), config.HistoryDays)
 
 	data := MockData{}
 
@@ -420,16 +771,26 @@ func generateMockData(config MockConfig) MockData {
 		data.Xoxo.Departments[dept.Code] = Department{
 			Code:      dept.Code,
 			Name:      dept.Name,
-			CreatedAt: now - int64(rand.Intn(30*24*3600*1000)),
+			CreatedAt: randomSetupTime(timeline),
 		}
 	}
 
+	// Phones and emails are unique across members and customers
+	contacts := newContactBook()
+
 	// Generate Members
 	data.Xoxo.Members = make(map[string]Member)
+	tenures := make(map[string]Tenure)
+
+	// Fixed members - always include these 3 members. They are the login
+	// accounts, so they have worked here throughout the history.
+	contacts.Reserve("0900000001", "admin@gmail.com")
+	contacts.Reserve("0900000002", "sale31@gmail.com")
+	contacts.Reserve("0900000003", "kt@gmail.com")
 
-	// Fixed members - always include these 3 members
 	// Admin member
 	adminID := "ADMIN_FIXED_001"
+	tenures[adminID] = planTenure("veteran", timeline, now)
 	data.Xoxo.Members[adminID] = Member{
 		Code:        adminID,
 		ID:          adminID,
@@ -438,13 +799,11 @@ func generateMockData(config MockConfig) MockData {
 		Email:       "admin@gmail.com",
 		Role:        "admin",
 		DateOfBirth: "1985-01-01",
-		IsActive:    true,
-		CreatedAt:   now - int64(90*24*3600*1000), // Created 90 days ago
-		UpdatedAt:   now - int64(90*24*3600*1000),
 	}
 
 	// Sales member
 	salesID := "SALES_FIXED_001"
+	tenures[salesID] = planTenure("veteran", timeline, now)
 	data.Xoxo.Members[salesID] = Member{
 		Code:        salesID,
 		ID:          salesID,
@@ -453,9 +812,6 @@ func generateMockData(config MockConfig) MockData {
 		Email:       "sale31@gmail.com",
 		Role:        "sales",
 		DateOfBirth: "1990-01-01",
-		IsActive:    true,
-		CreatedAt:   now - int64(90*24*3600*1000), // Created 90 days ago
-		UpdatedAt:   now - int64(90*24*3600*1000),
 	}
 
 	// Worker (Kỹ thuật) member
@@ -469,6 +825,7 @@ func generateMockData(config MockConfig) MockData {
 	if firstDeptCode != "" {
 		workerDepts = []string{firstDeptCode}
 	}
+	tenures[workerID] = planTenure("veteran", timeline, now)
 	data.Xoxo.Members[workerID] = Member{
 		Code:        workerID,
 		ID:          workerID,
@@ -478,63 +835,63 @@ func generateMockData(config MockConfig) MockData {
 		Role:        "worker",
 		Departments: workerDepts,
 		DateOfBirth: "1992-01-01",
-		IsActive:    true,
-		CreatedAt:   now - int64(90*24*3600*1000), // Created 90 days ago
-		UpdatedAt:   now - int64(90*24*3600*1000),
 	}
 
 	// Generate sales members
 	for i := 0; i < config.NumSalesMembers; i++ {
 		id := generateID("SALES", i)
-		name := randomName()
+		gender := randomGender()
+		name := randomName(gender)
 		member := Member{
 			Code:        id,
 			ID:          id,
 			Name:        name,
-			Phone:       randomPhone(),
-			Email:       randomEmail(name),
+			Gender:      gender,
+			Phone:       contacts.Phone(),
+			Email:       contacts.Email(name),
 			Role:        "sales",
 			DateOfBirth: randomDateOfBirth(),
-			IsActive:    true,
-			CreatedAt:   now - int64(rand.Intn(30*24*3600*1000)),
 		}
 		data.Xoxo.Members[id] = member
+		tenures[id] = planTenure(memberLifecycleScenarios[i%len(memberLifecycleScenarios)], timeline, now)
 	}
 
 	// Generate admin members
 	for i := 0; i < config.NumAdminMembers; i++ {
 		id := generateID("ADMIN", i)
-		name := randomName()
+		gender := randomGender()
+		name := randomName(gender)
 		member := Member{
 			Code:        id,
 			ID:          id,
 			Name:        name,
-			Phone:       randomPhone(),
-			Email:       randomEmail(name),
+			Gender:      gender,
+			Phone:       contacts.Phone(),
+			Email:       contacts.Email(name),
 			Role:        "admin",
 			DateOfBirth: randomDateOfBirth(),
-			IsActive:    true,
-			CreatedAt:   now - int64(rand.Intn(30*24*3600*1000)),
 		}
 		data.Xoxo.Members[id] = member
+		tenures[id] = planTenure(memberLifecycleScenarios[i%len(memberLifecycleScenarios)], timeline, now)
 	}
 
 	// Generate dev members
 	for i := 0; i < config.NumDevMembers; i++ {
 		id := generateID("DEV", i)
-		name := randomName()
+		gender := randomGender()
+		name := randomName(gender)
 		member := Member{
 			Code:        id,
 			ID:          id,
 			Name:        name,
-			Phone:       randomPhone(),
-			Email:       randomEmail(name),
+			Gender:      gender,
+			Phone:       contacts.Phone(),
+			Email:       contacts.Email(name),
 			Role:        "development",
 			DateOfBirth: randomDateOfBirth(),
-			IsActive:    true,
-			CreatedAt:   now - int64(rand.Intn(30*24*3600*1000)),
 		}
 		data.Xoxo.Members[id] = member
+		tenures[id] = planTenure(memberLifecycleScenarios[i%len(memberLifecycleScenarios)], timeline, now)
 	}
 
 	// Generate worker members (with departments)
@@ -542,24 +899,41 @@ func generateMockData(config MockConfig) MockData {
 	for _, dept := range deptList {
 		for j := 0; j < config.NumWorkersPerDept; j++ {
 			id := generateID("WORKER", workerIndex)
-			name := randomName()
+			gender := randomGender()
+			name := randomName(gender)
 			member := Member{
 				Code:        id,
 				ID:          id,
 				Name:        name,
-				Phone:       randomPhone(),
-				Email:       randomEmail(name),
+				Gender:      gender,
+				Phone:       contacts.Phone(),
+				Email:       contacts.Email(name),
 				Role:        "worker",
 				Departments: []string{dept.Code},
 				DateOfBirth: randomDateOfBirth(),
-				IsActive:    true,
-				CreatedAt:   now - int64(rand.Intn(30*24*3600*1000)),
 			}
 			data.Xoxo.Members[id] = member
+			tenures[id] = planTenure(memberLifecycleScenarios[j%len(memberLifecycleScenarios)], timeline, now)
 			workerIndex++
 		}
 	}
 
+	// Fill HR profiles in code order so timesheet codes follow the member codes
+	memberIDs := make([]string, 0, len(data.Xoxo.Members))
+	for id := range data.Xoxo.Members {
+		memberIDs = append(memberIDs, id)
+	}
+	sort.Strings(memberIDs)
+	usedIDCards := make(map[string]bool)
+	for i, id := range memberIDs {
+		member := data.Xoxo.Members[id]
+		applyTenure(&member, tenures[id])
+		fillMemberProfile(&member, i, usedIDCards)
+		data.Xoxo.Members[id] = member
+	}
+	// Work is only assigned to members on staff at the time it happens
+	roster := newRoster(data.Xoxo.Members, tenures)
+
 	// Generate Workflows (linked to departments)
 	data.Xoxo.Workflows = make(map[string]Workflow)
 	workflowIndex := 0
@@ -579,60 +953,56 @@ func generateMockData(config MockConfig) MockData {
 	data.Xoxo.Categories = make(map[string]Category)
 	for i := 0; i < config.NumCategories && i < len(categoryNames); i++ {
 		categoryCode := fmt.Sprintf("CAT_%03d", i+1)
+		createdAt := randomSetupTime(timeline)
 		data.Xoxo.Categories[categoryCode] = Category{
 			Code:        categoryCode,
 			Name:        categoryNames[i],
 			Description: fmt.Sprintf("Danh mục %s", categoryNames[i]),
 			Color:       categoryColors[i%len(categoryColors)],
-			CreatedAt:   now - int64(rand.Intn(30*24*3600*1000)),
-			UpdatedAt:   now - int64(rand.Intn(30*24*3600*1000)),
+			CreatedAt:   createdAt,
+			UpdatedAt:   timeline.Between(createdAt, now),
 		}
 	}
 
-	// Generate Materials (linked to categories)
+	// Generate Materials (linked to categories). Stock is set further down by
+	// replaying the inventory ledger; plans hold the balance each should end on.
 	data.Xoxo.Materials = make(map[string]Material)
-	materialCategoryMap := map[string]string{
-		"Vải cotton": "Vải",
-		"Vải denim":  "Vải",
-		"Vải lụa":    "Vải",
-		"Vải thun":   "Vải",
-		"Chỉ may":    "Phụ liệu",
-		"Khóa kéo":   "Phụ liệu",
-		"Khuy áo":    "Phụ liệu",
-		"Da bò":      "Da",
-		"Bông vải":   "Vải",
-		"Túi vải":    "Bao bì",
-	}
-
-	materialUnitMap := map[string]string{
-		"Vải cotton": "m2",
-		"Vải denim":  "m2",
-		"Vải lụa":    "m2",
-		"Vải thun":   "m2",
-		"Chỉ may":    "cuon",
-		"Khóa kéo":   "cai",
-		"Khuy áo":    "cai",
-		"Da bò":      "m2",
-		"Bông vải":   "kg",
-		"Túi vải":    "cai",
-	}
-
+	stockPlans := make(map[string]StockPlan)
+	perishableIndex := 0
 	for i := 0; i < config.NumMaterials && i < len(materialNames); i++ {
 		materialName := materialNames[i]
 		materialID := generateMaterialCode(i)
-		category := materialCategoryMap[materialName]
+		category := materialCategories[materialName]
 		if category == "" {
 			category = categoryNames[rand.Intn(len(categoryNames))]
 		}
-		unit := materialUnitMap[materialName]
+		unit := materialUnits[materialName]
 		if unit == "" {
 			unit = units[rand.Intn(len(units))]
 		}
 
-		stockQuantity := 100 + rand.Intn(900)
-		minThreshold := 50 + rand.Intn(100)
-		maxCapacity := stockQuantity + 500 + rand.Intn(1000)
-		importPrice := 10000 + rand.Intn(100000)
+		// Thresholds and stock are in the material's unit, around its reorder
+		// level and the size of one purchase
+		typicalImport := materialImports[materialName]
+		minThreshold := max(2, materialReorder[materialName]*(80+rand.Intn(41))/100)
+		alertThreshold := minThreshold * 2
+		longStockAlertDays := 30 + rand.Intn(60)
+		lastMovementDaysAgo := rand.Intn(30)
+		stockQuantity := alertThreshold + rand.Intn(typicalImport+1)
+
+		// Cycle through scenarios so the warnings in InventoryManagement always have cases to show
+		switch materialStockScenarios[i%len(materialStockScenarios)] {
+		case "low_stock":
+			stockQuantity = rand.Intn(minThreshold)
+		case "near_alert":
+			// InventoryManagement warns when stock is within 30% below alertThreshold
+			nearAlert := (alertThreshold*7 + 9) / 10
+			stockQuantity = nearAlert + rand.Intn(max(1, alertThreshold-nearAlert))
+		case "long_stock":
+			lastMovementDaysAgo = longStockAlertDays + 1 + rand.Intn(60)
+		}
+		maxCapacity := stockQuantity + typicalImport*(1+rand.Intn(2))
+		importPrice := materialImportPrice(materialName)
 
 		material := Material{
 			ID:                 materialID,
@@ -642,259 +1012,112 @@ func generateMockData(config MockConfig) MockData {
 			Unit:               unit,
 			MinThreshold:       minThreshold,
 			MaxCapacity:        maxCapacity,
+			AlertThreshold:     alertThreshold,
+			Warehouse:          warehouseNames[i%len(warehouseNames)],
 			Supplier:           supplierNames[rand.Intn(len(supplierNames))],
+			Image:              imageStore.Add("materials/"+materialID+".png", "material", materialID, materialName),
 			ImportPrice:        importPrice,
-			LastUpdated:        time.Now().AddDate(0, 0, -rand.Intn(30)).Format("2006-01-02"),
-			LongStockAlertDays: 30 + rand.Intn(60),
-			CreatedAt:          now - int64(rand.Intn(60*24*3600*1000)),
-			UpdatedAt:          now - int64(rand.Intn(7*24*3600*1000)),
+			LongStockAlertDays: longStockAlertDays,
+			CreatedAt:          timeline.Between(timeline.Start()-60*dayMillis, timeline.Start()),
+		}
+		lastMovementFrom := now - int64(lastMovementDaysAgo+1)*dayMillis
+		stockPlans[materialID] = StockPlan{
+			TargetStock:    stockQuantity,
+			LastMovementAt: timeline.Between(lastMovementFrom, lastMovementFrom+dayMillis),
+		}
+
+		if perishableCategories[category] {
+			var expiryDate time.Time
+			switch expiryScenarios[perishableIndex%len(expiryScenarios)] {
+			case "expired":
+				expiryDate = time.Now().AddDate(0, 0, -(1 + rand.Intn(60)))
+			case "near_expiry":
+				expiryDate = time.Now().AddDate(0, 0, rand.Intn(30))
+			default:
+				expiryDate = time.Now().AddDate(0, 3+rand.Intn(21), 0)
+			}
+			material.ExpiryDate = expiryDate.Format("2006-01-02")
+			perishableIndex++
 		}
+
 		data.Xoxo.Materials[materialID] = material
 	}
 
-	// Get sales member IDs for createdBy
-	salesMemberIDs := make([]string, 0)
-	for id, member := range data.Xoxo.Members {
-		if member.Role == "sales" {
-			salesMemberIDs = append(salesMemberIDs, id)
+	// Default inventory settings (InventoryService.getSettings)
+	data.Xoxo.Inventory.Settings = InventorySettings{
+		DefaultLongStockDays: 90,
+		UpdatedAt:            now,
+	}
+
+	// Schedule orders first so customers can be given purchase histories
+	schedules := make([]OrderSchedule, config.NumOrders)
+	orderDates := make([]int64, config.NumOrders)
+	for i := range schedules {
+		schedules[i] = scheduleOrder(timeline, orderStatusScenarios[i%len(orderStatusScenarios)], now)
+		orderDates[i] = schedules[i].OrderDate
+	}
+
+	// Generate Customers, numbered in order of their first purchase. Contact
+	// details and source stay the same on every order a customer places.
+	data.Xoxo.Customers = make(map[string]Customer)
+	customerPlans := planCustomers(orderDates, config.LapsedCustomerRate, now)
+	customerCodes := make([]string, config.NumOrders)
+	for c, plan := range customerPlans {
+		customer := newCustomer(generateID("CUST", c), contacts, config.DuplicatePhoneRate, orderDates[plan.Orders[0]], orderDates[plan.Orders[len(plan.Orders)-1]])
+		data.Xoxo.Customers[customer.Code] = customer
+		for _, orderIdx := range plan.Orders {
+			customerCodes[orderIdx] = customer.Code
 		}
 	}
 
 	// Generate Orders
 	data.Xoxo.Orders = make(map[string]FirebaseOrderData)
 	orderCodes := make([]string, 0)
+	completedOrderIdx := make([]int, 0)
 
 	for i := 0; i < config.NumOrders; i++ {
-		orderID := fmt.Sprintf("ORD_%03d", i+1)
-		orderCode := fmt.Sprintf("ORD%04d%02d%02d%03d",
-			time.Now().Year(),
-			int(time.Now().Month()),
-			time.Now().Day(),
-			i+1)
-
-		createdBy := salesMemberIDs[rand.Intn(len(salesMemberIDs))]
-		createdByName := data.Xoxo.Members[createdBy].Name
-
-		orderDate := now - int64(rand.Intn(30*24*3600*1000))
-		deliveryDate := orderDate + int64((3+rand.Intn(10))*24*3600*1000)
-
-		// Generate products for this order
-		numProducts := 1 + rand.Intn(3)
-		products := make(map[string]FirebaseProductData)
-
-		for j := 0; j < numProducts; j++ {
-			productID := fmt.Sprintf("PROD_%s_%d", orderID, j+1)
-			productName := productNames[rand.Intn(len(productNames))]
-			quantity := 10 + rand.Intn(100)
-			price := 50000 + rand.Intn(500000)
-
-			// Generate workflows for this product
-			productWorkflows := make(map[string]FirebaseWorkflowData)
-
-			selectedDepts := make([]string, 0)
-			numDepts := 2 + rand.Intn(3)
-			deptIndices := rand.Perm(len(deptList))[:numDepts]
-
-			for _, idx := range deptIndices {
-				dept := deptList[idx]
-				selectedDepts = append(selectedDepts, dept.Code)
-			}
-
-			workflowIndexInProduct := 0
-			for _, deptCode := range selectedDepts {
-				availableWorkflows := make([]string, 0)
-				for wfID, wf := range data.Xoxo.Workflows {
-					if wf.Department == deptCode {
-						availableWorkflows = append(availableWorkflows, wfID)
-					}
-				}
-
-				if len(availableWorkflows) > 0 {
-					numWorkflows := 1 + rand.Intn(2)
-					if numWorkflows > len(availableWorkflows) {
-						numWorkflows = len(availableWorkflows)
-					}
-
-					selectedWorkflowIDs := availableWorkflows[:numWorkflows]
-					workflowCodes := make([]string, 0)
-					workflowNamesList := make([]string, 0)
-
-					for _, wfID := range selectedWorkflowIDs {
-						workflowCodes = append(workflowCodes, wfID)
-						workflowNamesList = append(workflowNamesList, data.Xoxo.Workflows[wfID].Name)
-					}
-
-					availableMembers := make([]string, 0)
-					for memberID, member := range data.Xoxo.Members {
-						if member.Role == "worker" {
-							for _, memberDept := range member.Departments {
-								if memberDept == deptCode {
-									availableMembers = append(availableMembers, memberID)
-									break
-								}
-							}
-						}
-					}
-
-					numMembers := 1 + rand.Intn(2)
-					if numMembers > len(availableMembers) {
-						numMembers = len(availableMembers)
-					}
-
-					assignedMembers := make([]string, 0)
-					if numMembers > 0 {
-						memberIndices := rand.Perm(len(availableMembers))[:numMembers]
-						for _, idx := range memberIndices {
-							assignedMembers = append(assignedMembers, availableMembers[idx])
-						}
-					}
-
-					isDone := workflowIndexInProduct < 2 && rand.Float32() < 0.7
-
-					workflowID := fmt.Sprintf("workflow_%s_%d", productID, workflowIndexInProduct)
-					productWorkflows[workflowID] = FirebaseWorkflowData{
-						DepartmentCode: deptCode,
-						WorkflowCode:   workflowCodes,
-						WorkflowName:   workflowNamesList,
-						Members:        assignedMembers,
-						IsDone:         isDone,
-						UpdatedAt:      orderDate + int64(workflowIndexInProduct*3600*1000),
-					}
-					workflowIndexInProduct++
-				}
-			}
-
-			imageURL := "https://firebasestorage.googleapis.com/v0/b/morata-8e8e4.appspot.com/o/images%2Fproduct.jpg?alt=media&token=2d68623c-9ee8-4c1d-905b-c5155ba427ed"
-			numImages := 1 + rand.Intn(3)
-			images := make([]Image, 0)
-			for k := 0; k < numImages; k++ {
-				images = append(images, Image{
-					UID:  fmt.Sprintf("img_%s_%d", productID, k),
-					Name: fmt.Sprintf("product_%d.jpg", k+1),
-					URL:  imageURL,
-				})
-			}
-
-			var imagesDone []Image
-			hasCompletedWorkflows := false
-			for _, wf := range productWorkflows {
-				if wf.IsDone {
-					hasCompletedWorkflows = true
-					break
-				}
-			}
-			if hasCompletedWorkflows && rand.Float32() < 0.6 {
-				numImagesDone := 1 + rand.Intn(2)
-				imagesDone = make([]Image, 0)
-				for k := 0; k < numImagesDone; k++ {
-					imagesDone = append(imagesDone, Image{
-						UID:  fmt.Sprintf("img_done_%s_%d", productID, k),
-						Name: fmt.Sprintf("product_done_%d.jpg", k+1),
-						URL:  imageURL,
-					})
-				}
-			}
-
-			products[productID] = FirebaseProductData{
-				Name:                 productName,
-				Quantity:             quantity,
-				Price:                price,
-				CommissionPercentage: 5.0 + rand.Float64()*10.0,
-				Images:               images,
-				ImagesDone:           imagesDone,
-				Workflows:            productWorkflows,
-			}
-		}
-
-		subtotal := 0
-		for _, product := range products {
-			subtotal += product.Price * product.Quantity
-		}
-
-		discountType := discountTypes[rand.Intn(len(discountTypes))]
-		discount := 0
-		if rand.Float32() < 0.5 {
-			if discountType == "percentage" {
-				discount = 5 + rand.Intn(15)
-			} else {
-				discount = 50000 + rand.Intn(200000)
-			}
-		}
-		discountAmount := 0
-		if discount > 0 {
-			if discountType == "percentage" {
-				discountAmount = (subtotal * discount) / 100
-			} else {
-				discountAmount = discount
-			}
-		}
-
-		shippingFee := 0
-		if rand.Float32() < 0.7 {
-			shippingFee = 20000 + rand.Intn(50000)
-		}
-
-		totalAmount := subtotal - discountAmount + shippingFee
-
-		deposit := 0
-		depositAmount := 0
-		isDepositPaid := false
-		if rand.Float32() < 0.6 {
-			deposit = 30 + rand.Intn(40)
-			depositAmount = (totalAmount * deposit) / 100
-			isDepositPaid = rand.Float32() < 0.8
+		status := orderStatusScenarios[i%len(orderStatusScenarios)]
+		orderID, order := generateOrder(i, status, schedules[i], data.Xoxo.Customers[customerCodes[i]], deptList, data.Xoxo.Workflows, roster, timeline, now)
+		data.Xoxo.Orders[orderID] = order
+		orderCodes = append(orderCodes, order.Code)
+		if status == "completed" {
+			completedOrderIdx = append(completedOrderIdx, i)
 		}
+	}
 
-		status := orderStatuses[rand.Intn(len(orderStatuses))]
-
-		order := FirebaseOrderData{
-			Code:           orderCode,
-			CustomerName:   randomName(),
-			Phone:          randomPhone(),
-			Email:          randomEmail(randomName()),
-			Address:        fmt.Sprintf("%d Đường %s, Quận %d, TP.HCM", 100+rand.Intn(900), randomName(), 1+rand.Intn(12)),
-			CustomerSource: customerSources[rand.Intn(len(customerSources))],
-			OrderDate:      orderDate,
-			DeliveryDate:   deliveryDate,
-			CreatedBy:      createdBy,
-			CreatedByName:  createdByName,
-			CreatedAt:      orderDate,
-			UpdatedAt:      orderDate + int64(rand.Intn(24*3600*1000)),
-			Notes:          fmt.Sprintf("Ghi chú cho đơn hàng %s", orderCode),
-			Discount:       discount,
-			DiscountType:   discountType,
-			ShippingFee:    shippingFee,
-			Products:       products,
-			Status:         status,
-			TotalAmount:    totalAmount,
-			DiscountAmount: discountAmount,
-			Subtotal:       subtotal,
-			Deposit:        deposit,
-			DepositType:    "percentage",
-			DepositAmount:  depositAmount,
-			IsDepositPaid:  isDepositPaid,
+	// Generate Customer Groups and place customers by their purchase history
+	data.Xoxo.CustomerGroups = make(map[string]CustomerGroup)
+	for _, tier := range customerGroupTiers {
+		data.Xoxo.CustomerGroups[tier.Code] = CustomerGroup{
+			Code:      tier.Code,
+			Name:      tier.Name,
+			CreatedAt: timeline.Start(),
+			UpdatedAt: timeline.Start(),
 		}
-
-		if rand.Float32() < 0.5 {
-			consultantID := salesMemberIDs[rand.Intn(len(salesMemberIDs))]
-			order.ConsultantID = consultantID
-			order.ConsultantName = data.Xoxo.Members[consultantID].Name
+	}
+	for _, plan := range customerPlans {
+		orders := make([]FirebaseOrderData, 0, len(plan.Orders))
+		for _, orderIdx := range plan.Orders {
+			orders = append(orders, data.Xoxo.Orders[fmt.Sprintf("ORD_%03d", orderIdx+1)])
 		}
-
-		data.Xoxo.Orders[orderID] = order
+		customer := data.Xoxo.Customers[customerCodes[plan.Orders[0]]]
+		customer.CustomerGroup = customerGroup(orders, plan.Lapsed)
+		data.Xoxo.Customers[customer.Code] = customer
 	}
 
 	// Generate Warranty Claims (linked to orders)
 	data.Xoxo.WarrantyClaims = make(map[string]WarrantyClaim)
 	warrantyOrderIDs := make([]string, 0)
-	if len(orderCodes) > 0 {
+	// Only orders handed back to the customer can be claimed under warranty
+	if len(completedOrderIdx) > 0 {
 		numWarrantyClaims := config.NumWarrantyClaims
-		if numWarrantyClaims > len(orderCodes) {
-			numWarrantyClaims = len(orderCodes)
+		if numWarrantyClaims > len(completedOrderIdx) {
+			numWarrantyClaims = len(completedOrderIdx)
 		}
-		selectedOrderIndices := rand.Perm(len(orderCodes))[:numWarrantyClaims]
+		selectedOrderIndices := rand.Perm(len(completedOrderIdx))[:numWarrantyClaims]
 
-		for i, orderIdx := range selectedOrderIndices {
+		for i, k := range selectedOrderIndices {
+			orderIdx := completedOrderIdx[k]
 			orderID := fmt.Sprintf("ORD_%03d", orderIdx+1)
 			orderCode := orderCodes[orderIdx]
 			order := data.Xoxo.Orders[orderID]
@@ -908,6 +1131,8 @@ func generateMockData(config MockConfig) MockData {
 				warrantyProducts[productID] = product
 			}
 
+			createdAt := timeline.Following(order.DeliveryDate, 30)
+			createdBy := roster.Assign(createdAt, order.CreatedBy, hasRole("sales"))
 			warrantyClaim := WarrantyClaim{
 				ID:                warrantyID,
 				Code:              warrantyCode,
@@ -920,15 +1145,15 @@ func generateMockData(config MockConfig) MockData {
 				CustomerSource:    order.CustomerSource,
 				OrderDate:         order.OrderDate,
 				DeliveryDate:      order.DeliveryDate,
-				CreatedBy:         order.CreatedBy,
-				CreatedByName:     order.CreatedByName,
+				CreatedBy:         createdBy,
+				CreatedByName:     roster.Name(createdBy),
 				Products:          warrantyProducts,
 				Status:            warrantyStatuses[rand.Intn(len(warrantyStatuses))],
 				TotalAmount:       order.TotalAmount,
 				Notes:             fmt.Sprintf("Khiếu nại cho đơn hàng %s", orderCode),
-				Issues:            []string{"Lỗi sản phẩm", "Không đúng mẫu"},
-				CreatedAt:         order.OrderDate + int64(rand.Intn(7*24*3600*1000)),
-				UpdatedAt:         order.OrderDate + int64(rand.Intn(10*24*3600*1000)),
+				Issues:            []string{"Bong lớp mạ", "Màu không đều so với mẫu"},
+				CreatedAt:         createdAt,
+				UpdatedAt:         timeline.Following(createdAt, 3),
 			}
 
 			data.Xoxo.WarrantyClaims[warrantyID] = warrantyClaim
@@ -936,298 +1161,714 @@ func generateMockData(config MockConfig) MockData {
 		}
 	}
 
-	// Generate Inventory Transactions (linked to materials)
-	data.Xoxo.InventoryTransactions = make(map[string]InventoryTransaction)
-	materialIDs := make([]string, 0)
-	for materialID := range data.Xoxo.Materials {
-		materialIDs = append(materialIDs, materialID)
+	// Generate Inventory Transactions (linked to materials) and derive stock from them
+	data.Xoxo.InventoryTransactions = buildInventoryLedger(data.Xoxo.Materials, stockPlans, int(math.Round(config.InventoryTxnsPerOrder*float64(config.NumOrders))), timeline)
+	replayInventoryLedger(data.Xoxo.Materials, data.Xoxo.InventoryTransactions)
+
+	// Generate Refunds (linked to orders). Only money actually received can be
+	// refunded: cancelled orders get their payments back in full, the others a
+	// part of what was paid.
+	data.Xoxo.Refunds = make(map[string]RefundRequest)
+	refundOrderIdx := make([]int, 0)
+	for _, orderIdx := range rand.Perm(len(orderCodes)) {
+		order := data.Xoxo.Orders[fmt.Sprintf("ORD_%03d", orderIdx+1)]
+		if order.TotalPaidAmount == 0 {
+			continue
+		}
+		if order.Status == "cancelled" {
+			refundOrderIdx = append([]int{orderIdx}, refundOrderIdx...)
+		} else {
+			refundOrderIdx = append(refundOrderIdx, orderIdx)
+		}
 	}
 
-	for i := 0; i < config.NumInventoryTxns && i < len(materialIDs)*2; i++ {
-		materialID := materialIDs[rand.Intn(len(materialIDs))]
-		material := data.Xoxo.Materials[materialID]
+	numRefunds := min(config.NumRefunds, len(refundOrderIdx))
+	for i, orderIdx := range refundOrderIdx[:numRefunds] {
+		orderID := fmt.Sprintf("ORD_%03d", orderIdx+1)
+		orderCode := orderCodes[orderIdx]
+		order := data.Xoxo.Orders[orderID]
 
-		txnCode := generateTransactionCode(i)
-		txnType := "import"
-		if rand.Float32() < 0.4 {
-			txnType = "export"
-		}
+		refundID := fmt.Sprintf("RF_%03d", i+1)
+		refundCode := generateRefundCode(i)
 
-		quantity := 10 + rand.Intn(100)
-		if txnType == "export" && quantity > material.StockQuantity {
-			quantity = material.StockQuantity / 2
+		refundAmount := order.TotalPaidAmount
+		reason := "Khách hàng hủy đơn"
+		if order.Status != "cancelled" {
+			refundAmount = min(roundVND(order.TotalAmount*(10+rand.Intn(21))/100, 10000), order.TotalPaidAmount)
+			reason = "Khách hàng yêu cầu hoàn tiền"
 		}
 
-		price := material.ImportPrice
-		if price == 0 {
-			price = 10000 + rand.Intn(100000)
+		refundType := refundTypes[rand.Intn(len(refundTypes))]
+		refundStatus := refundStatusScenarios[i%len(refundStatusScenarios)]
+		lastPaidAt := order.Payments[len(order.Payments)-1].PaidAt
+		requestedAt := timeline.Following(max(order.UpdatedAt, lastPaidAt), 7)
+		updatedAt := requestedAt
+		requestedBy := roster.Assign(requestedAt, order.CreatedBy, hasRole("sales"))
+
+		refund := RefundRequest{
+			ID:              refundID,
+			OrderID:         orderID,
+			OrderCode:       orderCode,
+			Amount:          refundAmount,
+			Reason:          reason,
+			Type:            refundType,
+			Status:          refundStatus,
+			RequestedBy:     requestedBy,
+			RequestedByName: roster.Name(requestedBy),
+			RequestedAt:     requestedAt,
+			CreatedAt:       requestedAt,
+			Notes:           fmt.Sprintf("Ghi chú cho yêu cầu hoàn tiền %s", refundCode),
 		}
-		totalAmount := quantity * price
-
-		date := time.Now().AddDate(0, 0, -rand.Intn(30))
-		txn := InventoryTransaction{
-			Code:         txnCode,
-			MaterialID:   materialID,
-			MaterialName: material.Name,
-			Type:         txnType,
-			Quantity:     quantity,
-			Unit:         material.Unit,
-			Price:        price,
-			TotalAmount:  totalAmount,
-			Date:         date.Format("2006-01-02"),
-			Supplier:     material.Supplier,
-			Reason:       "",
-			Note:         fmt.Sprintf("Giao dịch %s cho %s", txnType, material.Name),
-			CreatedAt:    date.Unix() * 1000,
+
+		switch refundStatus {
+		case "approved", "processed":
+			refund.ApprovedAt = timeline.Following(requestedAt, 2)
+			refund.ApprovedBy = roster.Pick(refund.ApprovedAt, hasRole("admin"))
+			refund.ApprovedByName = roster.Name(refund.ApprovedBy)
+			updatedAt = refund.ApprovedAt
+		case "rejected":
+			refund.RejectedAt = timeline.Following(requestedAt, 2)
+			refund.RejectedBy = roster.Pick(refund.RejectedAt, hasRole("admin"))
+			refund.RejectedByName = roster.Name(refund.RejectedBy)
+			refund.RejectionReason = "Không đủ điều kiện hoàn tiền"
+			updatedAt = refund.RejectedAt
 		}
 
-		if txnType == "export" {
-			reasons := []string{"Sản xuất", "Bán hàng", "Kiểm tra", "Hư hỏng"}
-			txn.Reason = reasons[rand.Intn(len(reasons))]
+		if refundStatus == "processed" {
+			refund.ProcessedDate = timeline.Following(refund.ApprovedAt, 3)
+			refund.ProcessedBy = roster.Pick(refund.ProcessedDate, hasRole("admin"))
+			refund.ProcessedByName = roster.Name(refund.ProcessedBy)
+			updatedAt = refund.ProcessedDate
 		}
+		refund.UpdatedAt = updatedAt
 
-		data.Xoxo.InventoryTransactions[txnCode] = txn
+		data.Xoxo.Refunds[refundID] = refund
 	}
 
-	// Generate Finance Transactions (linked to orders, inventory, refunds)
-	data.Xoxo.FinanceTransactions = make(map[string]FinanceTransaction)
-	financeIndex := 0
+	// Generate Suppliers and pay for every purchase in the inventory ledger
+	financeAdmin := data.Xoxo.Members["ADMIN_FIXED_001"]
+	data.Xoxo.Suppliers = generateSuppliers(contacts, timeline.Start())
+	supplierPayments := generateSupplierPayments(data.Xoxo.Suppliers, data.Xoxo.InventoryTransactions, financeAdmin, timeline)
+	data.Xoxo.SupplierPayments = make(map[string]SupplierPayment, len(supplierPayments))
+	for _, payment := range supplierPayments {
+		data.Xoxo.SupplierPayments[payment.Code] = payment
+	}
+
+	// Generate Finance Transactions as a projection of the money events above
+	data.Xoxo.Finance.Transactions = buildFinanceLedger(&data, supplierPayments, config.NumManualFinanceTxns, financeAdmin, roster, timeline, now)
+
+	// Generate Feedbacks (linked to orders)
+	data.Xoxo.Feedbacks = make(map[string]CustomerFeedback)
+	// Feedback is collected after the customer has their items back
+	if len(completedOrderIdx) > 0 {
+		numFeedbacks := config.NumFeedbacks
+		if numFeedbacks > len(completedOrderIdx) {
+			numFeedbacks = len(completedOrderIdx)
+		}
+		selectedFeedbackIndices := rand.Perm(len(completedOrderIdx))[:numFeedbacks]
 
-	// Finance transactions from orders
-	for orderID, order := range data.Xoxo.Orders {
-		if order.Status == "confirmed" || order.Status == "completed" {
-			financeCode := generateFinanceCode(financeIndex)
-			financeIndex++
+		for i, k := range selectedFeedbackIndices {
+			orderIdx := completedOrderIdx[k]
+			orderID := fmt.Sprintf("ORD_%03d", orderIdx+1)
+			orderCode := orderCodes[orderIdx]
+			order := data.Xoxo.Orders[orderID]
 
-			amount := order.DepositAmount
-			if order.Status == "completed" {
-				amount = order.TotalAmount - order.DepositAmount
+			feedbackID := fmt.Sprintf("FB_%03d", i+1)
+			collectedAt := timeline.Following(order.DeliveryDate, 3)
+			collectedBy := roster.Assign(collectedAt, order.CreatedBy, hasRole("sales"))
+			feedbackType := feedbackTypes[rand.Intn(len(feedbackTypes))]
+			rating := 3 + rand.Intn(3)
+			if feedbackType == "Chê" || feedbackType == "Bức xúc" {
+				rating = 1 + rand.Intn(2)
 			}
 
-			financeTxn := FinanceTransaction{
-				ID:          financeCode,
-				Date:        order.OrderDate,
-				Type:        "income",
-				Category:    "order",
-				Amount:      amount,
-				Description: fmt.Sprintf("Đơn hàng %s", order.Code),
-				Reference:   order.Code,
-				SourceID:    orderID,
-				SourceType:  "order",
-				CreatedAt:   order.OrderDate,
-				UpdatedAt:   order.UpdatedAt,
+			feedback := CustomerFeedback{
+				ID:              feedbackID,
+				OrderID:         orderID,
+				OrderCode:       orderCode,
+				CustomerName:    order.CustomerName,
+				CustomerPhone:   order.Phone,
+				FeedbackType:    feedbackType,
+				Rating:          rating,
+				Notes:           fmt.Sprintf("Feedback cho đơn hàng %s", orderCode),
+				CollectedBy:     collectedBy,
+				CollectedByName: roster.Name(collectedBy),
+				CollectedAt:     collectedAt,
+				CreatedAt:       collectedAt,
+				UpdatedAt:       collectedAt,
 			}
-			data.Xoxo.FinanceTransactions[financeCode] = financeTxn
+
+			data.Xoxo.Feedbacks[feedbackID] = feedback
 		}
 	}
 
-	// Finance transactions from inventory imports
-	for txnCode, txn := range data.Xoxo.InventoryTransactions {
-		if txn.Type == "import" && financeIndex < config.NumFinanceTxns {
-			financeCode := generateFinanceCode(financeIndex)
-			financeIndex++
-
-			financeTxn := FinanceTransaction{
-				ID:          financeCode,
-				Date:        txn.CreatedAt,
-				Type:        "expense",
-				Category:    "inventory",
-				Amount:      txn.TotalAmount,
-				Description: fmt.Sprintf("Nhập kho %s", txn.MaterialName),
-				Reference:   txnCode,
-				SourceID:    txn.MaterialID,
-				SourceType:  "inventory",
-				CreatedAt:   txn.CreatedAt,
-				UpdatedAt:   txn.CreatedAt,
-			}
-			data.Xoxo.FinanceTransactions[financeCode] = financeTxn
+	// Generate Operational Workflows (one per department, jobs follow the department workflows)
+	data.Xoxo.OperationalWorkflows = make(map[string]OperationalWorkflow)
+	sortedMaterialIDs := make([]string, 0, len(data.Xoxo.Materials))
+	for materialID := range data.Xoxo.Materials {
+		sortedMaterialIDs = append(sortedMaterialIDs, materialID)
+	}
+	sort.Strings(sortedMaterialIDs)
+
+	randomWorkflowMaterials := func() []OperationalWorkflowMaterial {
+		materials := make([]OperationalWorkflowMaterial, 0)
+		if len(sortedMaterialIDs) == 0 {
+			return materials
+		}
+		numMaterials := 1 + rand.Intn(2)
+		for _, idx := range rand.Perm(len(sortedMaterialIDs))[:min(numMaterials, len(sortedMaterialIDs))] {
+			material := data.Xoxo.Materials[sortedMaterialIDs[idx]]
+			materials = append(materials, OperationalWorkflowMaterial{
+				MaterialID:   material.ID,
+				MaterialName: material.Name,
+				Quantity:     1 + rand.Intn(5),
+				Unit:         material.Unit,
+			})
 		}
+		return materials
 	}
 
-	// Generate Refunds (linked to orders)
-	data.Xoxo.Refunds = make(map[string]RefundRequest)
-	refundOrderIDs := make([]string, 0)
-	if len(orderCodes) > 0 {
-		numRefunds := config.NumRefunds
-		if numRefunds > len(orderCodes) {
-			numRefunds = len(orderCodes)
+	operationalWorkflowIDs := make([]string, 0)
+	for i, dept := range deptList {
+		workflowID := generateID("OPW", i)
+		// Set up before the items of the last 30 days that follow it.
+		createdAt := timeline.Between(now-60*dayMillis, now-30*dayMillis)
+
+		jobs := make([]OperationalWorkflowJob, 0)
+		for j, jobName := range workflowNames[dept.Code] {
+			jobID := fmt.Sprintf("%s_JOB_%d", workflowID, j+1)
+			tasks := make([]OperationalWorkflowTask, 0)
+			for k, taskName := range operationalTaskNames {
+				tasks = append(tasks, OperationalWorkflowTask{
+					ID:        fmt.Sprintf("%s_TASK_%d", jobID, k+1),
+					TaskName:  fmt.Sprintf("%s - %s", taskName, jobName),
+					TaskOrder: k + 1,
+				})
+			}
+			jobs = append(jobs, OperationalWorkflowJob{
+				ID:        jobID,
+				JobName:   jobName,
+				JobOrder:  j + 1,
+				Tasks:     tasks,
+				Materials: randomWorkflowMaterials(),
+			})
+		}
+		if len(jobs) == 0 {
+			continue
 		}
-		selectedRefundIndices := rand.Perm(len(orderCodes))[:numRefunds]
 
-		for i, orderIdx := range selectedRefundIndices {
-			orderID := fmt.Sprintf("ORD_%03d", orderIdx+1)
-			orderCode := orderCodes[orderIdx]
-			order := data.Xoxo.Orders[orderID]
+		data.Xoxo.OperationalWorkflows[workflowID] = OperationalWorkflow{
+			WorkflowName:   fmt.Sprintf("Quy trình %s", dept.Name),
+			DepartmentCode: dept.Code,
+			Jobs:           jobs,
+			Materials:      randomWorkflowMaterials(),
+			CreatedAt:      createdAt,
+			UpdatedAt:      timeline.Following(createdAt, 7),
+		}
+		operationalWorkflowIDs = append(operationalWorkflowIDs, workflowID)
+	}
 
-			refundID := fmt.Sprintf("RF_%03d", i+1)
-			refundCode := generateRefundCode(i)
+	sortedOrderIDs := make([]string, 0, len(data.Xoxo.Orders))
+	for orderID := range data.Xoxo.Orders {
+		sortedOrderIDs = append(sortedOrderIDs, orderID)
+	}
+	sort.Strings(sortedOrderIDs)
+
+	// Generate Operational Workflow Items (round-robin over workflows and statuses so every state appears)
+	data.Xoxo.OperationalWorkflowItems = make(map[string]OperationalWorkflowItem)
+	for i := 0; i < config.NumOperationalWorkflowItems && len(operationalWorkflowIDs) > 0; i++ {
+		itemID := generateID("OPWI", i)
+		workflowID := operationalWorkflowIDs[i%len(operationalWorkflowIDs)]
+		workflow := data.Xoxo.OperationalWorkflows[workflowID]
+		job := workflow.Jobs[rand.Intn(len(workflow.Jobs))]
+		status := operationalItemStatuses[i%len(operationalItemStatuses)]
+
+		createdAt := timeline.Between(now-30*dayMillis, now)
+		item := OperationalWorkflowItem{
+			WorkflowID:   workflowID,
+			WorkflowName: workflow.WorkflowName,
+			JobID:        job.ID,
+			JobName:      job.JobName,
+			JobOrder:     job.JobOrder,
+			Status:       status,
+			CreatedAt:    createdAt,
+			UpdatedAt:    createdAt,
+		}
 
-			refundAmount := order.TotalAmount / 2
-			if order.DepositAmount > 0 {
-				refundAmount = order.DepositAmount
+		// Two thirds of the items come from orders, the rest are self-created with notes and images
+		if len(sortedOrderIDs) > 0 && rand.Float32() < 0.67 {
+			order := data.Xoxo.Orders[sortedOrderIDs[rand.Intn(len(sortedOrderIDs))]]
+			productIDs := make([]string, 0, len(order.Products))
+			for productID := range order.Products {
+				productIDs = append(productIDs, productID)
 			}
+			sort.Strings(productIDs)
+			productID := productIDs[rand.Intn(len(productIDs))]
+
+			item.OrderCode = order.Code
+			item.ProductID = productID
+			item.ProductName = order.Products[productID].Name
+			item.CustomerName = order.CustomerName
+			if order.OrderDate > createdAt {
+				item.CreatedAt = order.OrderDate
+				item.UpdatedAt = order.OrderDate
+			}
+		} else {
+			item.Notes = fmt.Sprintf("Công việc nội bộ: %s", job.JobName)
+			item.Images = []string{imageStore.Add(fmt.Sprintf("operational_workflow_items/%s_1.png", itemID), "task", itemID, job.JobName)}
+		}
 
-			refundType := refundTypes[rand.Intn(len(refundTypes))]
-			refundStatus := refundStatuses[rand.Intn(len(refundStatuses))]
-			requestedAt := order.OrderDate + int64(rand.Intn(7*24*3600*1000))
-			updatedAt := requestedAt + int64(rand.Intn(3*24*3600*1000))
+		if workers := roster.Active(item.CreatedAt, inDepartment(workflow.DepartmentCode)); len(workers) > 0 {
+			assignedTo := workers[i%len(workers)]
+			item.AssignedTo = assignedTo
+			item.AssignedToName = roster.Name(assignedTo)
+		}
 
-			refund := RefundRequest{
-				ID:              refundID,
-				OrderID:         orderID,
-				OrderCode:       orderCode,
-				Amount:          refundAmount,
-				Reason:          "Khách hàng yêu cầu hoàn tiền",
-				Type:            refundType,
-				Status:          refundStatus,
-				RequestedBy:     order.CreatedBy,
-				RequestedByName: order.CreatedByName,
-				RequestedAt:     requestedAt,
-				CreatedAt:       requestedAt,
-				UpdatedAt:       updatedAt,
-				Notes:           fmt.Sprintf("Ghi chú cho yêu cầu hoàn tiền %s", refundCode),
+		switch status {
+		case "pending":
+			if job.JobOrder > 1 {
+				item.StartedAt = timeline.Following(item.CreatedAt, 1)
+				item.UpdatedAt = item.StartedAt
 			}
+		case "completed":
+			item.StartedAt = timeline.Following(item.CreatedAt, 1)
+			item.CompletedAt = timeline.Following(item.StartedAt, 2)
+			durationMs := item.CompletedAt - item.StartedAt
+			item.DurationHours = math.Round(float64(durationMs)/(1000*60*60)*100) / 100
+			item.UpdatedAt = item.CompletedAt
+		case "cancelled":
+			item.CancelledAt = timeline.Following(item.CreatedAt, 3)
+			item.CancelReason = cancelReasons[rand.Intn(len(cancelReasons))]
+			item.ConfirmedCancelled = rand.Float32() < 0.5
+			item.CancelledBy = roster.Pick(item.CancelledAt, hasRole("admin"))
+			item.CancelledByName = roster.Name(item.CancelledBy)
+			item.UpdatedAt = item.CancelledAt
+		}
+
+		data.Xoxo.OperationalWorkflowItems[itemID] = item
+	}
+
+	// Generate Standalone Tasks (assigned across departments, cycling through statuses)
+	data.Xoxo.StandaloneTasks = make(map[string]StandaloneTask)
+	for i := 0; i < config.NumStandaloneTasks; i++ {
+		taskID := generateID("TASK", i)
+		status := standaloneTaskStatuses[i%len(standaloneTaskStatuses)]
+		createdAt := timeline.Between(now-14*dayMillis, now)
+
+		workers := roster.Active(createdAt, hasRole("worker"))
+		if len(workers) == 0 {
+			continue
+		}
+		assignee := workers[i%len(workers)]
+		createdBy := assignee
+		if admin := roster.Pick(createdAt, hasRole("admin")); admin != "" {
+			createdBy = admin
+		}
+
+		data.Xoxo.StandaloneTasks[taskID] = StandaloneTask{
+			Title:       standaloneTaskTitles[rand.Intn(len(standaloneTaskTitles))],
+			Description: fmt.Sprintf("Giao cho %s", roster.Name(assignee)),
+			Assignee:    assignee,
+			Deadline:    timeline.After(createdAt, 1, 7),
+			CreatedAt:   createdAt,
+			CreatedBy:   createdBy,
+			Status:      status,
+			IsDone:      status == "completed",
+			Type:        standaloneTaskTypes[rand.Intn(len(standaloneTaskTypes))],
+		}
+	}
+
+	return data
+}
+
+// generateOrder creates the order with index i, numbered ORD_%03d from 1,
+// for a customer: its products with their department workflows progressed
+// as far as status allows, their before images, pricing and the payments made
+// so far.
+func generateOrder(i int, status string, schedule OrderSchedule, customer Customer, deptList []VocabDepartment, workflows map[string]Workflow, roster *Roster, timeline *Timeline, now int64) (string, FirebaseOrderData) {
+	orderID := fmt.Sprintf("ORD_%03d", i+1)
+	orderDate := schedule.OrderDate
+	orderCode := fmt.Sprintf("ORD%s%03d", time.UnixMilli(orderDate).In(vietnamTime).Format("20060102"), i+1)
+
+	createdBy := roster.Pick(orderDate, hasRole("sales"))
+	createdByName := roster.Name(createdBy)
+
+	// Generate products for this order
+	numProducts := 1 + rand.Intn(3)
+	products := make(map[string]FirebaseProductData)
+
+	for j := 0; j < numProducts; j++ {
+		productID := fmt.Sprintf("PROD_%s_%d", orderID, j+1)
+		productType := productTypes[rand.Intn(len(productTypes))]
+		service := productType.Services[rand.Intn(len(productType.Services))]
+		productName := fmt.Sprintf("%s - %s", productType.Items[rand.Intn(len(productType.Items))], service.Name)
+		quantity := 1 + rand.Intn(2)
+		price := servicePrice(service)
+
+		// Generate workflows for this product
+		productWorkflows := make(map[string]FirebaseWorkflowData)
+
+		// Items go through the departments in the order they are listed,
+		// and through a department's workflows in ID order, so the workflows
+		// progressWorkflows marks done first are the ones done first.
+		selectedDepts := make([]string, 0)
+		numDepts := min(2+rand.Intn(3), len(deptList))
+		deptIndices := rand.Perm(len(deptList))[:numDepts]
+		sort.Ints(deptIndices)
+
+		for _, idx := range deptIndices {
+			dept := deptList[idx]
+			selectedDepts = append(selectedDepts, dept.Code)
+		}
 
-			adminMembers := make([]string, 0)
-			for id, member := range data.Xoxo.Members {
-				if member.Role == "admin" {
-					adminMembers = append(adminMembers, id)
+		workflowIndexInProduct := 0
+		for _, deptCode := range selectedDepts {
+			availableWorkflows := make([]string, 0)
+			for wfID, wf := range workflows {
+				if wf.Department == deptCode {
+					availableWorkflows = append(availableWorkflows, wfID)
 				}
 			}
 
-			if refundStatus == "approved" || refundStatus == "processed" {
-				if len(adminMembers) > 0 {
-					approvedBy := adminMembers[rand.Intn(len(adminMembers))]
-					refund.ApprovedBy = approvedBy
-					refund.ApprovedByName = data.Xoxo.Members[approvedBy].Name
-					refund.ApprovedAt = requestedAt + int64(rand.Intn(2*24*3600*1000))
+			if len(availableWorkflows) > 0 {
+				sort.Strings(availableWorkflows)
+				numWorkflows := 1 + rand.Intn(2)
+				if numWorkflows > len(availableWorkflows) {
+					numWorkflows = len(availableWorkflows)
 				}
-			}
 
-			if refundStatus == "rejected" {
-				if len(adminMembers) > 0 {
-					rejectedBy := adminMembers[rand.Intn(len(adminMembers))]
-					refund.RejectedBy = rejectedBy
-					refund.RejectedByName = data.Xoxo.Members[rejectedBy].Name
-					refund.RejectedAt = requestedAt + int64(rand.Intn(2*24*3600*1000))
-					refund.RejectionReason = "Không đủ điều kiện hoàn tiền"
+				workflowIndices := rand.Perm(len(availableWorkflows))[:numWorkflows]
+				sort.Ints(workflowIndices)
+				selectedWorkflowIDs := make([]string, 0, numWorkflows)
+				for _, idx := range workflowIndices {
+					selectedWorkflowIDs = append(selectedWorkflowIDs, availableWorkflows[idx])
 				}
-			}
+				workflowCodes := make([]string, 0)
+				workflowNamesList := make([]string, 0)
 
-			if refundStatus == "processed" {
-				if len(adminMembers) > 0 {
-					processedBy := adminMembers[rand.Intn(len(adminMembers))]
-					refund.ProcessedBy = processedBy
-					refund.ProcessedByName = data.Xoxo.Members[processedBy].Name
-					refund.ProcessedDate = updatedAt
+				for _, wfID := range selectedWorkflowIDs {
+					workflowCodes = append(workflowCodes, wfID)
+					workflowNamesList = append(workflowNamesList, workflows[wfID].Name)
+				}
+
+				availableMembers := roster.Active(orderDate, inDepartment(deptCode))
+
+				numMembers := 1 + rand.Intn(2)
+				if numMembers > len(availableMembers) {
+					numMembers = len(availableMembers)
+				}
+
+				assignedMembers := make([]string, 0)
+				if numMembers > 0 {
+					memberIndices := rand.Perm(len(availableMembers))[:numMembers]
+					for _, idx := range memberIndices {
+						assignedMembers = append(assignedMembers, availableMembers[idx])
+					}
 				}
-			}
 
-			data.Xoxo.Refunds[refundID] = refund
-			refundOrderIDs = append(refundOrderIDs, orderID)
-
-			// Add finance transaction for processed refunds
-			if refund.Status == "processed" && financeIndex < config.NumFinanceTxns {
-				financeCode := generateFinanceCode(financeIndex)
-				financeIndex++
-
-				financeTxn := FinanceTransaction{
-					ID:          financeCode,
-					Date:        refund.UpdatedAt,
-					Type:        "expense",
-					Category:    "order",
-					Amount:      refund.Amount,
-					Description: fmt.Sprintf("Hoàn tiền đơn hàng %s", orderCode),
-					Reference:   refundCode,
-					SourceID:    refundID,
-					SourceType:  "refund",
-					CreatedAt:   refund.UpdatedAt,
-					UpdatedAt:   refund.UpdatedAt,
+				workflowID := fmt.Sprintf("workflow_%s_%d", productID, workflowIndexInProduct)
+				productWorkflows[workflowID] = FirebaseWorkflowData{
+					DepartmentCode: deptCode,
+					WorkflowCode:   workflowCodes,
+					WorkflowName:   workflowNamesList,
+					Members:        assignedMembers,
+					UpdatedAt:      orderDate,
 				}
-				data.Xoxo.FinanceTransactions[financeCode] = financeTxn
+				workflowIndexInProduct++
 			}
 		}
-	}
 
-	// Generate Feedbacks (linked to orders)
-	data.Xoxo.Feedbacks = make(map[string]CustomerFeedback)
-	if len(orderCodes) > 0 {
-		numFeedbacks := config.NumFeedbacks
-		if numFeedbacks > len(orderCodes) {
-			numFeedbacks = len(orderCodes)
+		numImages := 1 + rand.Intn(3)
+		images := make([]Image, 0)
+		for k := 0; k < numImages; k++ {
+			name := fmt.Sprintf("before_%d.png", k+1)
+			images = append(images, Image{
+				UID:  fmt.Sprintf("img_%s_%d", productID, k),
+				Name: name,
+				URL: imageStore.Add(fmt.Sprintf("orders/%s/%s/%s", orderCode, productID, name), "before",
+					orderCode, productID, productName, fmt.Sprintf("%d/%d", k+1, numImages)),
+			})
 		}
-		selectedFeedbackIndices := rand.Perm(len(orderCodes))[:numFeedbacks]
 
-		for i, orderIdx := range selectedFeedbackIndices {
-			orderID := fmt.Sprintf("ORD_%03d", orderIdx+1)
-			orderCode := orderCodes[orderIdx]
-			order := data.Xoxo.Orders[orderID]
+		products[productID] = FirebaseProductData{
+			Name:                 productName,
+			Quantity:             quantity,
+			Price:                price,
+			CommissionPercentage: commissionPercentages[rand.Intn(len(commissionPercentages))],
+			Images:               images,
+			Workflows:            productWorkflows,
+		}
+	}
 
-			feedbackID := fmt.Sprintf("FB_%03d", i+1)
-			feedbackType := feedbackTypes[rand.Intn(len(feedbackTypes))]
-			rating := 3 + rand.Intn(3)
-			if feedbackType == "Chê" || feedbackType == "Bức xúc" {
-				rating = 1 + rand.Intn(2)
-			}
+	lastDoneAt := progressWorkflows(orderCode, products, status, schedule, timeline, now)
 
-			feedback := CustomerFeedback{
-				ID:              feedbackID,
-				OrderID:         orderID,
-				OrderCode:       orderCode,
-				CustomerName:    order.CustomerName,
-				CustomerPhone:   order.Phone,
-				FeedbackType:    feedbackType,
-				Rating:          rating,
-				Notes:           fmt.Sprintf("Feedback cho đơn hàng %s", orderCode),
-				CollectedBy:     order.CreatedBy,
-				CollectedByName: order.CreatedByName,
-				CollectedAt:     order.DeliveryDate + int64(rand.Intn(3*24*3600*1000)),
-				CreatedAt:       order.DeliveryDate + int64(rand.Intn(3*24*3600*1000)),
-				UpdatedAt:       order.DeliveryDate + int64(rand.Intn(3*24*3600*1000)),
-			}
+	subtotal := 0
+	for _, product := range products {
+		subtotal += product.Price * product.Quantity
+	}
 
-			data.Xoxo.Feedbacks[feedbackID] = feedback
+	discountType := discountTypes[rand.Intn(len(discountTypes))]
+	discount := 0
+	if rand.Float32() < 0.5 {
+		discount = randomDiscount(discountType)
+	}
+	discountAmount := 0
+	if discount > 0 {
+		if discountType == "percentage" {
+			discountAmount = (subtotal * discount) / 100
+		} else {
+			discountAmount = discount
 		}
 	}
 
-	return data
+	shippingFee := 0
+	if rand.Float32() < 0.7 {
+		shippingFee = shippingFees[rand.Intn(len(shippingFees))]
+	}
+
+	totalAmount := subtotal - discountAmount + shippingFee
+
+	deposit := 0
+	depositType := "percentage"
+	depositAmount := 0
+	isDepositPaid := false
+	// OrderForm requires a deposit before an order can be confirmed; once
+	// work has started the deposit has been paid.
+	if status != "pending" && status != "cancelled" || rand.Float32() < 0.5 {
+		deposit, depositType, depositAmount = randomDeposit(totalAmount)
+		isDepositPaid = status != "pending"
+	}
+
+	updatedAt := schedule.StateAt
+	notes := fmt.Sprintf("Ghi chú cho đơn hàng %s", orderCode)
+	var issues []string
+	switch status {
+	case "in_progress":
+		updatedAt = max(updatedAt, lastDoneAt)
+	case "on_hold":
+		updatedAt = timeline.Following(max(updatedAt, lastDoneAt), 2)
+		issues = []string{orderHoldReasons[rand.Intn(len(orderHoldReasons))]}
+	case "cancelled":
+		notes = fmt.Sprintf("Hủy đơn: %s", orderCancelReasons[rand.Intn(len(orderCancelReasons))])
+	}
+
+	order := FirebaseOrderData{
+		Code:           orderCode,
+		CustomerName:   customer.Name,
+		Phone:          customer.Phone,
+		Email:          customer.Email,
+		Address:        customer.Address,
+		CustomerSource: customer.CustomerSource,
+		CustomerCode:   customer.Code,
+		OrderDate:      orderDate,
+		DeliveryDate:   schedule.DeliveryDate,
+		CreatedBy:      createdBy,
+		CreatedByName:  createdByName,
+		CreatedAt:      orderDate,
+		UpdatedAt:      updatedAt,
+		Notes:          notes,
+		Discount:       discount,
+		DiscountType:   discountType,
+		ShippingFee:    shippingFee,
+		Products:       products,
+		Status:         status,
+		TotalAmount:    totalAmount,
+		DiscountAmount: discountAmount,
+		Subtotal:       subtotal,
+		Deposit:        deposit,
+		DepositType:    depositType,
+		DepositAmount:  depositAmount,
+		IsDepositPaid:  isDepositPaid,
+		Issues:         issues,
+	}
+
+	if rand.Float32() < 0.5 {
+		consultantID := roster.Pick(orderDate, hasRole("sales"))
+		order.ConsultantID = consultantID
+		order.ConsultantName = roster.Name(consultantID)
+	}
+
+	order.Payments = orderPayments(orderID, order, schedule, roster, timeline, now)
+	order.TotalPaidAmount = sumPayments(order.Payments)
+	if status != "cancelled" {
+		order.RemainingDebt = order.TotalAmount - order.TotalPaidAmount
+	}
+
+	return orderID, order
 }
 
 func main() {
+	command := "generate"
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "export" {
+		if err := exportCommand(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting database: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "serve" {
+		if err := serveCommand(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error serving database: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "test-rules" {
+		if err := testRulesCommand(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error testing rules: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "rules" {
+		if err := rulesCommand(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating rules: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "diff" {
+		if err := diffCommand(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error comparing datasets: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "purge" {
+		if err := purgeCommand(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error purging: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(args) > 0 && (args[0] == "seed" || args[0] == "append") {
+		command, args = args[0], args[1:]
+	}
+
 	config := defaultConfig
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	vocabName := flags.String("vocab", defaultVocabName, "vocabulary pack: built-in name ("+strings.Join(builtinVocabNames(), ", ")+") or path to a JSON file")
+	flags.IntVar(&config.HistoryDays, "days", config.HistoryDays, "length in days of the history that business events are spread over")
+	flags.Float64Var(&config.DuplicatePhoneRate, "duplicate-phones", 0, "share of customers (0-1) that reuse another person's phone number")
+	// Append adds orders to a history it does not rebuild: customer lapses
+	// and the cash reconciliation are left to a full generation.
+	var financeReportPath string
+	if command != "append" {
+		flags.Float64Var(&config.LapsedCustomerRate, "lapsed-customers", config.LapsedCustomerRate, "share of customers (0-1) that stop ordering well before the end of the history")
+		flags.IntVar(&config.OpeningCashBalance, "opening-cash", config.OpeningCashBalance, "cash on hand in VND at the start of the history")
+		flags.StringVar(&financeReportPath, "finance-report", "", "also write the per-day and per-category cash reconciliation to this JSON file")
+	}
+	var seedOptions SeedOptions
+	var appendOptions AppendOptions
+	switch command {
+	case "seed":
+		seedOptions.register(flags)
+	case "append":
+		appendOptions.register(flags)
+	}
+	flags.Parse(args)
+	switch command {
+	case "seed":
+		imageStore = newImageStore(seedOptions.Images.EmulatorHost, seedOptions.Images.bucket(seedOptions.ProjectID))
+	case "append":
+		imageStore = newImageStore(appendOptions.Images.EmulatorHost, appendOptions.Images.bucket(appendOptions.ProjectID))
+	}
+	if config.HistoryDays < 1 {
+		fmt.Fprintf(os.Stderr, "Error: -days must be at least 1, got %d\n", config.HistoryDays)
+		os.Exit(1)
+	}
 
-	// Allow config override via environment variables or command line args
-	// For now, use default config
-	data := generateMockData(config)
+	vocab, err := loadVocabulary(*vocabName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading vocabulary: %v\n", err)
+		os.Exit(1)
+	}
+	applyVocabulary(vocab)
 
-	jsonData, err := json.MarshalIndent(data, "", "  ")
+	adminUnits, err := loadAdminUnits()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error marshaling JSON: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error loading administrative units: %v\n", err)
+		os.Exit(1)
+	}
+	addresses, err = newAddressBook(adminUnits, vocab.AddressWeights)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading vocabulary %q: %v\n", vocab.Name, err)
 		os.Exit(1)
 	}
 
-	outputFile := "./mock-data.json"
-	if len(os.Args) > 1 {
-		outputFile = os.Args[1]
+	if command == "append" {
+		if err := appendCommand(config, appendOptions, flags.Arg(0)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error appending orders: %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	err = os.WriteFile(outputFile, jsonData, 0644)
+	data := generateMockData(config)
+
+	report, err := reconcileFinance(data, config.OpeningCashBalance)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error reconciling finance ledger: %v\n", err)
 		os.Exit(1)
 	}
+	if financeReportPath != "" {
+		if err := writeFinanceReport(report, financeReportPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing finance report: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	switch command {
+	case "seed":
+		if err := seedDatabase(data, seedOptions); err != nil {
+			fmt.Fprintf(os.Stderr, "Error seeding database: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Mock data generated successfully with vocabulary %q! Seeded %s\n", vocab.Name, seedOptions.DatabaseURL)
+	default:
+		jsonData, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error marshaling JSON: %v\n", err)
+			os.Exit(1)
+		}
+
+		outputFile := "./mock-data.json"
+		if flags.NArg() > 0 {
+			outputFile = flags.Arg(0)
+		}
+
+		err = os.WriteFile(outputFile, jsonData, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Mock data generated successfully with vocabulary %q! Written to %s\n", vocab.Name, outputFile)
+	}
+
+	printSummary(data, report)
+}
 
-	fmt.Printf("Mock data generated successfully! Written to %s\n", outputFile)
+func printSummary(data MockData, report *FinanceReport) {
 	fmt.Printf("Generated:\n")
 	fmt.Printf("  - %d departments\n", len(data.Xoxo.Departments))
 	fmt.Printf("  - %d members\n", len(data.Xoxo.Members))
 	fmt.Printf("  - %d workflows\n", len(data.Xoxo.Workflows))
 	fmt.Printf("  - %d categories\n", len(data.Xoxo.Categories))
 	fmt.Printf("  - %d materials\n", len(data.Xoxo.Materials))
+	fmt.Printf("  - %d customers\n", len(data.Xoxo.Customers))
+	fmt.Printf("  - %d customer groups\n", len(data.Xoxo.CustomerGroups))
 	fmt.Printf("  - %d orders\n", len(data.Xoxo.Orders))
 	fmt.Printf("  - %d warranty claims\n", len(data.Xoxo.WarrantyClaims))
 	fmt.Printf("  - %d inventory transactions\n", len(data.Xoxo.InventoryTransactions))
-	fmt.Printf("  - %d finance transactions\n", len(data.Xoxo.FinanceTransactions))
+	fmt.Printf("  - %d suppliers\n", len(data.Xoxo.Suppliers))
+	fmt.Printf("  - %d supplier payments\n", len(data.Xoxo.SupplierPayments))
+	fmt.Printf("  - %d finance transactions\n", len(data.Xoxo.Finance.Transactions))
 	fmt.Printf("  - %d refunds\n", len(data.Xoxo.Refunds))
 	fmt.Printf("  - %d feedbacks\n", len(data.Xoxo.Feedbacks))
+	fmt.Printf("  - %d operational workflows\n", len(data.Xoxo.OperationalWorkflows))
+	fmt.Printf("  - %d operational workflow items\n", len(data.Xoxo.OperationalWorkflowItems))
+	fmt.Printf("  - %d standalone tasks\n", len(data.Xoxo.StandaloneTasks))
+	fmt.Printf("Cash: opening %d đ, income %d đ, expense %d đ, closing %d đ\n",
+		report.OpeningBalance, report.TotalIncome, report.TotalExpense, report.ClosingBalance)
 }
@@ -0,0 +1,73 @@
+package main
+
+import "math/rand"
+
+// Surcharges on a service's list price for oversized or badly worn items,
+// in percent. Most jobs are billed at the list price.
+var serviceSurcharges = []int{0, 0, 0, 0, 20, 30, 50}
+
+// Percentage discounts stay on whole tens so that, applied to subtotals built
+// from 50,000 đ service prices, the discount is a round amount as well.
+var discountPercentages = []int{10, 20, 30}
+
+var shippingFees = []int{20000, 25000, 30000, 35000, 40000, 50000}
+
+var depositPercentages = []int{30, 50, 70}
+
+var commissionPercentages = []float64{5, 8, 10, 12, 15}
+
+// roundVND rounds amount to the nearest multiple of step.
+func roundVND(amount, step int) int {
+	return (amount + step/2) / step * step
+}
+
+// priceStep is the rounding step a shop uses at a given price level:
+// hundreds for per-ml consumables, up to 50,000 đ for items over a million.
+func priceStep(amount int) int {
+	switch {
+	case amount < 10000:
+		return 100
+	case amount < 100000:
+		return 1000
+	case amount < 1000000:
+		return 10000
+	default:
+		return 50000
+	}
+}
+
+// servicePrice bills a service at its list price, sometimes with a surcharge,
+// rounded to 50,000 đ like the spa's printed price list.
+func servicePrice(service VocabService) int {
+	surcharge := serviceSurcharges[rand.Intn(len(serviceSurcharges))]
+	return max(roundVND(service.Price*(100+surcharge)/100, 50000), 50000)
+}
+
+// materialImportPrice varies a material's reference price by up to 10% either
+// way for supplier and batch differences.
+func materialImportPrice(material string) int {
+	price := materialPrices[material] * (90 + rand.Intn(21)) / 100
+	return max(roundVND(price, priceStep(price)), priceStep(price))
+}
+
+// randomDiscount returns a discount value for the given type: a whole-ten
+// percentage, or a fixed amount in steps of 50,000 đ.
+func randomDiscount(discountType string) int {
+	if discountType == "percentage" {
+		return discountPercentages[rand.Intn(len(discountPercentages))]
+	}
+	return 50000 * (1 + rand.Intn(6))
+}
+
+// randomDeposit picks a deposit for an order total. OrderForm derives
+// depositAmount from a percentage deposit, so a percentage is only used when
+// it comes out to a multiple of 10,000 đ; otherwise the deposit is a fixed
+// amount rounded down to 50,000 đ.
+func randomDeposit(totalAmount int) (deposit int, depositType string, depositAmount int) {
+	percentage := depositPercentages[rand.Intn(len(depositPercentages))]
+	if totalAmount*percentage%(100*10000) == 0 {
+		return percentage, "percentage", totalAmount * percentage / 100
+	}
+	amount := max(totalAmount*percentage/100/50000*50000, min(50000, totalAmount))
+	return amount, "amount", amount
+}
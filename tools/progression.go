@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// Order statuses cycle through this list so every status is present while
+// most orders in the history are completed.
+var orderStatusScenarios = []string{"completed", "in_progress", "completed", "pending", "completed", "confirmed", "cancelled", "completed", "on_hold", "completed"}
+
+// OrderSchedule holds the timestamps implied by an order's status.
+type OrderSchedule struct {
+	OrderDate    int64
+	DeliveryDate int64
+	// ConfirmedAt is when the deposit was taken; zero for pending orders.
+	ConfirmedAt int64
+	// StateAt is when the order reached its current status: confirmation,
+	// hand-over, cancellation or the latest progress for work in progress.
+	StateAt int64
+}
+
+// scheduleOrder places an order in time according to its status. Finished
+// and cancelled orders can be anywhere in the history; open orders are recent
+// and their delivery appointment is still ahead.
+func scheduleOrder(timeline *Timeline, status string, now int64) OrderSchedule {
+	var schedule OrderSchedule
+	switch status {
+	case "completed":
+		schedule.OrderDate = timeline.Between(timeline.Start(), now-12*dayMillis)
+	case "cancelled":
+		schedule.OrderDate = timeline.Between(timeline.Start(), now-dayMillis)
+	case "pending":
+		schedule.OrderDate = timeline.Between(now-3*dayMillis, now)
+	case "confirmed":
+		schedule.OrderDate = timeline.Between(now-5*dayMillis, now)
+	case "in_progress":
+		schedule.OrderDate = timeline.Between(now-10*dayMillis, now)
+	default:
+		schedule.OrderDate = timeline.Between(now-20*dayMillis, now)
+	}
+	if status != "pending" {
+		schedule.ConfirmedAt = timeline.Following(schedule.OrderDate, 1)
+	}
+
+	switch status {
+	case "completed":
+		schedule.DeliveryDate = timeline.After(schedule.OrderDate, 3, 12)
+		schedule.StateAt = schedule.DeliveryDate
+	case "cancelled":
+		schedule.DeliveryDate = timeline.After(schedule.OrderDate, 3, 12)
+		schedule.StateAt = timeline.Following(schedule.ConfirmedAt, 4)
+	default:
+		elapsedDays := int((now - schedule.OrderDate) / dayMillis)
+		schedule.DeliveryDate = timeline.After(schedule.OrderDate, elapsedDays+1, elapsedDays+10)
+		schedule.StateAt = max(schedule.OrderDate, schedule.ConfirmedAt)
+	}
+	return schedule
+}
+
+// progressWorkflows marks the workflows of each product done in order, as far
+// as the order status allows, and returns the time of the last finished step.
+// Products whose final step is done get their imagesDone.
+//
+//   - pending, confirmed: nothing done yet
+//   - in_progress: some steps done, at least one still open
+//   - on_hold, cancelled: stopped part way, never finished
+//   - completed: everything done before the delivery appointment
+func progressWorkflows(orderCode string, products map[string]FirebaseProductData, status string, schedule OrderSchedule, timeline *Timeline, now int64) int64 {
+	productIDs := make([]string, 0, len(products))
+	for productID := range products {
+		productIDs = append(productIDs, productID)
+	}
+	sort.Strings(productIDs)
+
+	doneCounts := make([]int, len(productIDs))
+	totalSteps, totalDone := 0, 0
+	for i, productID := range productIDs {
+		steps := len(products[productID].Workflows)
+		totalSteps += steps
+		switch status {
+		case "completed":
+			doneCounts[i] = steps
+		case "in_progress":
+			doneCounts[i] = rand.Intn(steps + 1)
+		case "on_hold", "cancelled":
+			doneCounts[i] = rand.Intn(max(steps, 1))
+		}
+		totalDone += doneCounts[i]
+	}
+	if status == "in_progress" && totalSteps > 1 {
+		if totalDone == 0 {
+			for i, productID := range productIDs {
+				if len(products[productID].Workflows) > 0 {
+					doneCounts[i] = 1
+					break
+				}
+			}
+		} else if totalDone == totalSteps {
+			for i := len(doneCounts) - 1; i >= 0; i-- {
+				if doneCounts[i] > 0 {
+					doneCounts[i]--
+					break
+				}
+			}
+		}
+	}
+
+	until := now
+	switch status {
+	case "completed", "cancelled":
+		until = schedule.StateAt
+	}
+
+	lastDoneAt := int64(0)
+	for i, productID := range productIDs {
+		product := products[productID]
+
+		doneTimes := make([]int64, doneCounts[i])
+		for k := range doneTimes {
+			doneTimes[k] = timeline.Between(schedule.OrderDate, until)
+		}
+		sort.Slice(doneTimes, func(a, b int) bool { return doneTimes[a] < doneTimes[b] })
+
+		steps := len(product.Workflows)
+		for k := 0; k < steps; k++ {
+			workflowID := fmt.Sprintf("workflow_%s_%d", productID, k)
+			workflow := product.Workflows[workflowID]
+			workflow.IsDone = k < doneCounts[i]
+			workflow.UpdatedAt = schedule.OrderDate
+			if workflow.IsDone {
+				workflow.UpdatedAt = doneTimes[k]
+				lastDoneAt = max(lastDoneAt, doneTimes[k])
+			}
+			product.Workflows[workflowID] = workflow
+		}
+
+		product.ImagesDone = nil
+		if steps > 0 && doneCounts[i] == steps {
+			numImagesDone := 1 + rand.Intn(2)
+			for k := 0; k < numImagesDone; k++ {
+				name := fmt.Sprintf("completion_%d.png", k+1)
+				product.ImagesDone = append(product.ImagesDone, Image{
+					UID:  fmt.Sprintf("img_done_%s_%d", productID, k),
+					Name: name,
+					URL: imageStore.Add(fmt.Sprintf("orders/%s/%s/%s", orderCode, productID, name), "after",
+						orderCode, productID, product.Name, fmt.Sprintf("%d/%d", k+1, numImagesDone)),
+				})
+			}
+		}
+		products[productID] = product
+	}
+	return lastDoneAt
+}
@@ -0,0 +1,619 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// purgeReference is a collection whose records point at records of another:
+// they are removed along with what they point at.
+type purgeReference struct {
+	Collection string
+	// KeyFields hold the referenced record's key, CodeFields its code.
+	KeyFields  []string
+	CodeFields []string
+	// SourceType, when set, is the sourceType a finance entry must have for
+	// its sourceId to count as a reference.
+	SourceType string
+}
+
+// purgeDependents lists, by collection, the records that reference its
+// records: the refunds, feedback, warranty claims, work items and finance
+// entries of an order, the finance entry of a refund and so on.
+var purgeDependents = map[string][]purgeReference{
+	"orders": {
+		{Collection: "refunds", KeyFields: []string{"orderId"}, CodeFields: []string{"orderCode"}},
+		{Collection: "feedbacks", KeyFields: []string{"orderId"}, CodeFields: []string{"orderCode"}},
+		{Collection: "warrantyClaims", KeyFields: []string{"originalOrderId"}, CodeFields: []string{"originalOrderCode"}},
+		{Collection: "operational_workflow_items", CodeFields: []string{"orderCode"}},
+		{Collection: "finance/transactions", KeyFields: []string{"sourceId"}, SourceType: "order"},
+	},
+	"refunds": {
+		{Collection: "finance/transactions", KeyFields: []string{"sourceId"}, SourceType: "refund"},
+	},
+	"materials": {
+		{Collection: "inventoryTransactions", KeyFields: []string{"materialId"}},
+	},
+	"inventoryTransactions": {
+		{Collection: "finance/transactions", KeyFields: []string{"sourceId"}, SourceType: "inventory"},
+	},
+	"suppliers": {
+		{Collection: "supplier_payments", KeyFields: []string{"supplierId"}},
+	},
+	"operational_workflows": {
+		{Collection: "operational_workflow_items", KeyFields: []string{"workflowId"}},
+	},
+}
+
+// purgeMention is a collection whose records name records of another only in
+// free text, such as a supplier payment's notes naming the goods receipt it
+// pays. They are not removed along with what they name but listed as left
+// dangling.
+type purgeMention struct {
+	Collection string
+	Field      string
+}
+
+var purgeMentions = map[string][]purgeMention{
+	"inventoryTransactions": {{Collection: "supplier_payments", Field: "notes"}},
+}
+
+// purgeDateFields are the fields that date a record, in order of preference:
+// when an order was placed or a payment made rather than when it was entered.
+var purgeDateFields = []string{"orderDate", "date", "paymentDate", "requestedAt", "collectedAt", "createdAt"}
+
+// PurgeOptions configures what the purge command removes.
+type PurgeOptions struct {
+	DatabaseOptions
+	Collections []string
+	// All removes the whole tree at the root, like delete-all-database.
+	All bool
+	// From and To bound the records' dates, To included; zero is unbounded.
+	From, To time.Time
+	// Prefix keeps records whose code, or key when they have none, starts
+	// with it.
+	Prefix string
+	// Dependents also removes the records referencing removed ones.
+	Dependents bool
+	DryRun     bool
+	Yes        bool
+}
+
+func (o *PurgeOptions) register(flags *flag.FlagSet) {
+	o.DatabaseOptions.register(flags)
+	flags.Func("collection", "collection to purge, e.g. orders or finance/transactions; repeatable", func(value string) error {
+		path := strings.Trim(value, "/")
+		if path == "" {
+			return fmt.Errorf("empty collection")
+		}
+		o.Collections = append(o.Collections, path)
+		return nil
+	})
+	flags.BoolVar(&o.All, "all", false, "remove the whole tree at the root")
+	date := func(t *time.Time) func(string) error {
+		return func(value string) error {
+			parsed, err := time.ParseInLocation("2006-01-02", value, vietnamTime)
+			if err != nil {
+				return fmt.Errorf("want a date like 2025-01-31")
+			}
+			*t = parsed
+			return nil
+		}
+	}
+	flags.Func("from", "only records dated on or after this day, e.g. 2025-01-01", date(&o.From))
+	flags.Func("to", "only records dated on or before this day, e.g. 2025-06-30", date(&o.To))
+	flags.StringVar(&o.Prefix, "prefix", "", "only records whose code (or key, without a code) starts with this, e.g. ORD2025")
+	flags.BoolVar(&o.Dependents, "dependents", true, "also remove records referencing removed ones, such as the refunds and finance entries of an order")
+	flags.BoolVar(&o.DryRun, "dry-run", false, "list what would be removed without removing it")
+	flags.BoolVar(&o.Yes, "yes", false, "confirm removing every record of a collection, or with -all the whole tree")
+}
+
+// recordDate returns the time a record is dated by, in milliseconds.
+func recordDate(record map[string]any) (int64, bool) {
+	for _, field := range purgeDateFields {
+		switch value := record[field].(type) {
+		case float64:
+			return int64(value), true
+		case string:
+			for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+				if t, err := time.ParseInLocation(layout, value, vietnamTime); err == nil {
+					return t.UnixMilli(), true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// matches reports whether a record falls within the scope of the filters.
+func (o PurgeOptions) matches(key string, record map[string]any) bool {
+	if o.Prefix != "" {
+		code, _ := record["code"].(string)
+		if code == "" {
+			code = key
+		}
+		if !strings.HasPrefix(code, o.Prefix) {
+			return false
+		}
+	}
+	if !o.From.IsZero() || !o.To.IsZero() {
+		at, ok := recordDate(record)
+		if !ok {
+			return false
+		}
+		if !o.From.IsZero() && at < o.From.UnixMilli() {
+			return false
+		}
+		if !o.To.IsZero() && at >= o.To.AddDate(0, 0, 1).UnixMilli() {
+			return false
+		}
+	}
+	return true
+}
+
+// scoped reports whether any filter narrows the purge down from whole
+// collections.
+func (o PurgeOptions) scoped() bool {
+	return o.Prefix != "" || !o.From.IsZero() || !o.To.IsZero()
+}
+
+// PurgeItem is a record to remove and, for a dependent, the record it
+// references.
+type PurgeItem struct {
+	Collection string
+	Key        string
+	Label      string
+	Because    string
+}
+
+func (i PurgeItem) path() string {
+	return i.Collection + "/" + i.Key
+}
+
+// purgeCollections lists the collections a purge reads: the selected ones and
+// those holding their dependents.
+func purgeCollections(selected []string, dependents bool) []string {
+	seen := make(map[string]bool)
+	var names []string
+	var visit func(name string)
+	visit = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		names = append(names, name)
+		if !dependents {
+			return
+		}
+		for _, reference := range purgeDependents[name] {
+			visit(reference.Collection)
+		}
+	}
+	for _, name := range selected {
+		visit(name)
+	}
+	return names
+}
+
+func recordLabel(key string, record map[string]any) string {
+	for _, field := range []string{"code", "name", "description", "title", "workflowName"} {
+		if text, _ := record[field].(string); text != "" && text != key {
+			return text
+		}
+	}
+	return ""
+}
+
+// planPurge picks the records of the selected collections within scope, then
+// the records referencing them, and theirs, until nothing more refers to a
+// removed record.
+func planPurge(tree map[string]any, options PurgeOptions) []PurgeItem {
+	records := func(collection string) map[string]any {
+		children, _ := getAt(tree, splitPath(collection)).(map[string]any)
+		return children
+	}
+
+	var items []PurgeItem
+	marked := make(map[string]bool)
+	var queue []PurgeItem
+	mark := func(item PurgeItem) {
+		if marked[item.path()] {
+			return
+		}
+		marked[item.path()] = true
+		record, _ := records(item.Collection)[item.Key].(map[string]any)
+		item.Label = recordLabel(item.Key, record)
+		items = append(items, item)
+		queue = append(queue, item)
+	}
+
+	for _, collection := range options.Collections {
+		keys := make([]string, 0)
+		for key, value := range records(collection) {
+			record, _ := value.(map[string]any)
+			if options.matches(key, record) {
+				keys = append(keys, key)
+			}
+		}
+		sortKeys(keys)
+		for _, key := range keys {
+			mark(PurgeItem{Collection: collection, Key: key})
+		}
+	}
+
+	for len(queue) > 0 && options.Dependents {
+		parent := queue[0]
+		queue = queue[1:]
+		parentRecord, _ := records(parent.Collection)[parent.Key].(map[string]any)
+		parentCode, _ := parentRecord["code"].(string)
+		for _, reference := range purgeDependents[parent.Collection] {
+			children := records(reference.Collection)
+			keys := make([]string, 0, len(children))
+			for key := range children {
+				keys = append(keys, key)
+			}
+			sortKeys(keys)
+			for _, key := range keys {
+				record, _ := children[key].(map[string]any)
+				if references(record, reference, parent.Key, parentCode) {
+					mark(PurgeItem{Collection: reference.Collection, Key: key, Because: parent.path()})
+				}
+			}
+		}
+	}
+	return items
+}
+
+func references(record map[string]any, reference purgeReference, key, code string) bool {
+	if reference.SourceType != "" && record["sourceType"] != reference.SourceType {
+		return false
+	}
+	for _, field := range reference.KeyFields {
+		if value, _ := record[field].(string); value != "" && value == key {
+			return true
+		}
+	}
+	for _, field := range reference.CodeFields {
+		if value, _ := record[field].(string); value != "" && value == code {
+			return true
+		}
+	}
+	return false
+}
+
+// purgeContext lists the collections a purge reads besides those it may
+// remove from: the customers whose orders go, for recomputing them, and the
+// records that may be left naming removed ones.
+func purgeContext(collections []string) []string {
+	var names []string
+	for _, name := range collections {
+		if name == "orders" {
+			names = append(names, "customers")
+		}
+		for _, mention := range purgeMentions[name] {
+			names = append(names, mention.Collection)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// danglingRecords lists the records, not themselves removed, that name a
+// removed record in free text, each with the record it names as Because.
+func danglingRecords(tree map[string]any, items []PurgeItem) []PurgeItem {
+	removed := make(map[string]bool, len(items))
+	for _, item := range items {
+		removed[item.path()] = true
+	}
+	var dangling []PurgeItem
+	seen := make(map[string]bool)
+	for _, item := range items {
+		records, _ := getAt(tree, splitPath(item.Collection)).(map[string]any)
+		record, _ := records[item.Key].(map[string]any)
+		names := []string{item.Key}
+		if code, _ := record["code"].(string); code != "" && code != item.Key {
+			names = append(names, code)
+		}
+		for _, mention := range purgeMentions[item.Collection] {
+			children, _ := getAt(tree, splitPath(mention.Collection)).(map[string]any)
+			keys := make([]string, 0, len(children))
+			for key := range children {
+				keys = append(keys, key)
+			}
+			sortKeys(keys)
+			for _, key := range keys {
+				child := PurgeItem{Collection: mention.Collection, Key: key}
+				if removed[child.path()] || seen[child.path()] {
+					continue
+				}
+				childRecord, _ := children[key].(map[string]any)
+				text, _ := childRecord[mention.Field].(string)
+				words := strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == ',' || r == ';' || r == '(' || r == ')' })
+				if slices.ContainsFunc(names, func(name string) bool { return slices.Contains(words, name) }) {
+					seen[child.path()] = true
+					child.Label = recordLabel(key, childRecord)
+					child.Because = item.path()
+					dangling = append(dangling, child)
+				}
+			}
+		}
+	}
+	return dangling
+}
+
+// customerRecomputes brings the customers whose orders are removed, and who
+// stay, back in line with the orders left: updatedAt follows their last
+// order, as the generator and the append command keep it, and their group
+// is worked out again. A lapsed customer stays lapsed. It returns the
+// update, by path, and the customers' keys.
+func customerRecomputes(tree map[string]any, items []PurgeItem) (map[string]any, []string) {
+	removed := make(map[string]bool, len(items))
+	for _, item := range items {
+		removed[item.path()] = true
+	}
+	orders, _ := tree["orders"].(map[string]any)
+	customers, _ := tree["customers"].(map[string]any)
+
+	affected := make(map[string]bool)
+	remaining := make(map[string][]FirebaseOrderData)
+	for key, value := range orders {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		var order FirebaseOrderData
+		if json.Unmarshal(raw, &order) != nil {
+			continue
+		}
+		if removed["orders/"+key] {
+			affected[order.CustomerCode] = true
+			continue
+		}
+		remaining[order.CustomerCode] = append(remaining[order.CustomerCode], order)
+	}
+
+	update := make(map[string]any)
+	var keys []string
+	for key, value := range customers {
+		record, _ := value.(map[string]any)
+		code, _ := record["code"].(string)
+		if code == "" {
+			code = key
+		}
+		if !affected[code] || removed["customers/"+key] {
+			continue
+		}
+		group, _ := record["customerGroup"].(string)
+		lapsed := group == customerGroupTiers[1].Code && len(remaining[code]) > 0
+		createdAt, _ := record["createdAt"].(float64)
+		updatedAt := int64(createdAt)
+		for _, order := range remaining[code] {
+			updatedAt = max(updatedAt, order.OrderDate)
+		}
+
+		path := "customers/" + key
+		if next := customerGroup(remaining[code], lapsed); next != group {
+			if next == "" {
+				update[path+"/customerGroup"] = nil
+			} else {
+				update[path+"/customerGroup"] = next
+			}
+		}
+		if current, _ := record["updatedAt"].(float64); int64(current) != updatedAt {
+			update[path+"/updatedAt"] = updatedAt
+		}
+		keys = append(keys, key)
+	}
+	sortKeys(keys)
+	return update, keys
+}
+
+// purgeUpdate is the multi-path update that removes the items: a collection
+// that loses every record is removed as a whole.
+func purgeUpdate(tree map[string]any, items []PurgeItem) map[string]any {
+	removed := make(map[string]int)
+	for _, item := range items {
+		removed[item.Collection]++
+	}
+	update := make(map[string]any)
+	for _, item := range items {
+		children, _ := getAt(tree, splitPath(item.Collection)).(map[string]any)
+		if removed[item.Collection] == len(children) {
+			update[item.Collection] = nil
+			continue
+		}
+		update[item.path()] = nil
+	}
+	return update
+}
+
+// purgeCommand runs `purge [flags] [mock-data.json]`: the deletions of the
+// admin pages (delete-all-database, delete-all-orders, delete-orders,
+// delete-operational-workflows) against a file, edited in place, or without
+// one against the database, as a single multi-path update.
+func purgeCommand(args []string) error {
+	var options PurgeOptions
+	flags := flag.NewFlagSet("purge", flag.ExitOnError)
+	options.register(flags)
+	flags.Parse(args)
+	file := flags.Arg(0)
+	root := strings.Trim(options.Root, "/")
+
+	switch {
+	case options.All && len(options.Collections) > 0:
+		return fmt.Errorf("-all removes every collection; drop -collection")
+	case !options.All && len(options.Collections) == 0:
+		return fmt.Errorf("name the collections to purge with -collection, or use -all")
+	case (options.All || !options.scoped()) && !options.DryRun && !options.Yes:
+		return fmt.Errorf("this removes whole collections; list them with -dry-run, then confirm with -yes")
+	}
+
+	var (
+		tree   any
+		client *RTDBClient
+		err    error
+		target string
+	)
+	existing := make(map[string]any)
+	if file != "" {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(raw, &tree); err != nil {
+			return fmt.Errorf("parse %s: %w", file, err)
+		}
+		existing, _ = getAt(tree, splitPath(root)).(map[string]any)
+		target = file
+	} else {
+		if client, err = options.client(); err != nil {
+			return err
+		}
+		target = fmt.Sprintf("%s/%s", client.baseURL, root)
+	}
+
+	if options.All {
+		if options.DryRun {
+			fmt.Printf("Would remove everything at /%s in %s\n", root, target)
+			return nil
+		}
+		if file != "" {
+			tree = setAt(tree, splitPath(root), nil)
+		} else if _, err := client.Do(http.MethodDelete, root, nil, nil); err != nil {
+			return fmt.Errorf("remove /%s: %w", root, err)
+		}
+	} else {
+		if file == "" {
+			reader := &treeReader{client: client, pageSize: 500}
+			collections := purgeCollections(options.Collections, options.Dependents)
+			for _, path := range append(collections, purgeContext(collections)...) {
+				if getAt(existing, splitPath(path)) != nil {
+					continue
+				}
+				value, err := reader.read(root + "/" + path)
+				if err != nil {
+					return fmt.Errorf("read %s: %w", path, err)
+				}
+				if value != nil {
+					var records any
+					if err := json.Unmarshal(value, &records); err != nil {
+						return fmt.Errorf("read %s: %w", path, err)
+					}
+					existing = setAt(existing, splitPath(path), records).(map[string]any)
+				}
+			}
+		}
+
+		items := planPurge(existing, options)
+		if len(items) == 0 {
+			fmt.Printf("Nothing to purge in %s\n", target)
+			return nil
+		}
+		counts := make(map[string]int)
+		for _, item := range items {
+			counts[item.Collection]++
+		}
+		verb := "Removing"
+		if options.DryRun {
+			verb = "Would remove"
+		}
+		collections := purgeCollections(options.Collections, options.Dependents)
+		sort.SliceStable(items, func(a, b int) bool {
+			return slices.Index(collections, items[a].Collection) < slices.Index(collections, items[b].Collection)
+		})
+		fmt.Printf("%s %d records from %s:\n", verb, len(items), target)
+		for _, collection := range collections {
+			if counts[collection] == 0 {
+				continue
+			}
+			fmt.Printf("  %-28s %d\n", collection, counts[collection])
+		}
+		recomputed, customers := customerRecomputes(existing, items)
+		if len(customers) > 0 {
+			recompute := "Recomputing"
+			if options.DryRun {
+				recompute = "Would recompute"
+			}
+			fmt.Printf("%s updatedAt and customerGroup of %d customers whose orders go\n", recompute, len(customers))
+		}
+		dangling := danglingRecords(existing, items)
+		if options.DryRun {
+			for _, item := range items {
+				line := "  - " + item.path()
+				if item.Label != "" {
+					line += " " + item.Label
+				}
+				if item.Because != "" {
+					line += " (references " + item.Because + ")"
+				}
+				fmt.Println(line)
+			}
+			paths := make([]string, 0, len(recomputed))
+			for path := range recomputed {
+				paths = append(paths, path)
+			}
+			sortKeys(paths)
+			for _, path := range paths {
+				fmt.Printf("  ~ %s = %s\n", path, formatFieldValue(recomputed[path]))
+			}
+		}
+		if len(dangling) > 0 {
+			fmt.Printf("%d records name removed records in free text and are left as they are:\n", len(dangling))
+			for _, item := range dangling {
+				line := "  ! " + item.path()
+				if item.Label != "" {
+					line += " " + item.Label
+				}
+				fmt.Println(line + " (names " + item.Because + ")")
+			}
+		}
+		if options.DryRun {
+			return nil
+		}
+
+		update := purgeUpdate(existing, items)
+		for path, value := range recomputed {
+			update[path] = value
+		}
+		if file != "" {
+			paths := make([]string, 0, len(update))
+			for path := range update {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			for _, path := range paths {
+				tree = setAt(tree, append(splitPath(root), splitPath(path)...), update[path])
+			}
+		} else {
+			body, err := json.Marshal(update)
+			if err != nil {
+				return fmt.Errorf("marshal update: %w", err)
+			}
+			if _, err := client.Do(http.MethodPatch, root, nil, body); err != nil {
+				return fmt.Errorf("write update: %w", err)
+			}
+		}
+	}
+
+	if file != "" {
+		if tree == nil {
+			tree = map[string]any{}
+		}
+		jsonData, err := json.MarshalIndent(tree, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal %s: %w", file, err)
+		}
+		if err := os.WriteFile(file, jsonData, 0644); err != nil {
+			return fmt.Errorf("write %s: %w", file, err)
+		}
+	}
+	fmt.Printf("Purged %s\n", target)
+	return nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// purgeTree is a small dataset: two orders of one customer, the refund and
+// finance entries of the first, and a goods receipt paid for by name.
+func purgeTree() map[string]any {
+	day := func(d int) float64 {
+		return float64(time.Date(2025, 3, d, 10, 0, 0, 0, vietnamTime).UnixMilli())
+	}
+	return map[string]any{
+		"orders": map[string]any{
+			"o1": map[string]any{"code": "ORD20250301", "customerCode": "KH001", "status": "completed", "totalAmount": float64(500000), "orderDate": day(1)},
+			"o2": map[string]any{"code": "ORD20250310", "customerCode": "KH001", "status": "completed", "totalAmount": float64(300000), "orderDate": day(10)},
+		},
+		"refunds": map[string]any{
+			"r1": map[string]any{"orderId": "o1", "orderCode": "ORD20250301", "createdAt": day(2)},
+		},
+		"feedbacks": map[string]any{
+			"f1": map[string]any{"orderCode": "ORD20250310", "createdAt": day(11)},
+		},
+		"finance": map[string]any{
+			"transactions": map[string]any{
+				"t1": map[string]any{"sourceType": "order", "sourceId": "o1", "date": day(1)},
+				"t2": map[string]any{"sourceType": "refund", "sourceId": "r1", "date": day(2)},
+				"t3": map[string]any{"sourceType": "inventory", "sourceId": "o1", "date": day(3)},
+				"t4": map[string]any{"sourceType": "inventory", "sourceId": "i1", "date": day(3)},
+			},
+		},
+		"customers": map[string]any{
+			"c1": map[string]any{"code": "KH001", "customerGroup": "GROUP_LOYAL", "createdAt": day(1), "updatedAt": day(10)},
+		},
+		"materials": map[string]any{
+			"m1": map[string]any{"name": "Da bò"},
+		},
+		"inventoryTransactions": map[string]any{
+			"i1": map[string]any{"code": "NK001", "materialId": "m1", "date": day(3)},
+		},
+		"supplier_payments": map[string]any{
+			"p1": map[string]any{"notes": "Thanh toán NK001, NK002", "paymentDate": day(4)},
+			"p2": map[string]any{"notes": "Thanh toán NK0010", "paymentDate": day(4)},
+		},
+	}
+}
+
+func TestPlanPurge(t *testing.T) {
+	date := func(d int) time.Time { return time.Date(2025, 3, d, 0, 0, 0, 0, vietnamTime) }
+	tests := []struct {
+		name    string
+		options PurgeOptions
+		want    []string
+	}{
+		{
+			name:    "orders with dependents",
+			options: PurgeOptions{Collections: []string{"orders"}, Dependents: true},
+			want: []string{
+				"orders/o1", "orders/o2",
+				"refunds/r1", "finance/transactions/t1",
+				"feedbacks/f1",
+				"finance/transactions/t2",
+			},
+		},
+		{
+			name:    "orders without dependents",
+			options: PurgeOptions{Collections: []string{"orders"}},
+			want:    []string{"orders/o1", "orders/o2"},
+		},
+		{
+			name:    "prefix",
+			options: PurgeOptions{Collections: []string{"orders"}, Prefix: "ORD202503", Dependents: true},
+			want: []string{
+				"orders/o1", "orders/o2",
+				"refunds/r1", "finance/transactions/t1",
+				"feedbacks/f1",
+				"finance/transactions/t2",
+			},
+		},
+		{
+			name:    "prefix matching nothing",
+			options: PurgeOptions{Collections: []string{"orders"}, Prefix: "ORD202504", Dependents: true},
+			want:    nil,
+		},
+		{
+			name:    "to includes its day",
+			options: PurgeOptions{Collections: []string{"orders"}, To: date(1), Dependents: true},
+			want:    []string{"orders/o1", "refunds/r1", "finance/transactions/t1", "finance/transactions/t2"},
+		},
+		{
+			name:    "from",
+			options: PurgeOptions{Collections: []string{"orders"}, From: date(2), Dependents: true},
+			want:    []string{"orders/o2", "feedbacks/f1"},
+		},
+		{
+			name:    "materials cascade to their stock movements and finance entries",
+			options: PurgeOptions{Collections: []string{"materials"}, Dependents: true},
+			want:    []string{"materials/m1", "inventoryTransactions/i1", "finance/transactions/t4"},
+		},
+		{
+			name:    "nested collection",
+			options: PurgeOptions{Collections: []string{"finance/transactions"}, From: date(3)},
+			want:    []string{"finance/transactions/t3", "finance/transactions/t4"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, item := range planPurge(purgeTree(), test.options) {
+				got = append(got, item.path())
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("planPurge = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPlanPurgeBecause(t *testing.T) {
+	items := planPurge(purgeTree(), PurgeOptions{Collections: []string{"refunds"}, Dependents: true})
+	want := []PurgeItem{
+		{Collection: "refunds", Key: "r1"},
+		{Collection: "finance/transactions", Key: "t2", Because: "refunds/r1"},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("planPurge = %+v, want %+v", items, want)
+	}
+}
+
+func TestDanglingRecords(t *testing.T) {
+	tree := purgeTree()
+	items := []PurgeItem{{Collection: "inventoryTransactions", Key: "i1"}}
+	got := danglingRecords(tree, items)
+	want := []PurgeItem{{Collection: "supplier_payments", Key: "p1", Because: "inventoryTransactions/i1"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("danglingRecords = %+v, want %+v", got, want)
+	}
+
+	items = append(items, PurgeItem{Collection: "supplier_payments", Key: "p1"})
+	if got := danglingRecords(tree, items); len(got) != 0 {
+		t.Errorf("danglingRecords listed a removed record: %+v", got)
+	}
+}
+
+func TestCustomerRecomputes(t *testing.T) {
+	created := time.Date(2025, 3, 1, 10, 0, 0, 0, vietnamTime).UnixMilli()
+	tests := []struct {
+		name    string
+		group   string
+		removed []string
+		want    map[string]any
+	}{
+		{
+			name:    "last order removed",
+			group:   "GROUP_LOYAL",
+			removed: []string{"o2"},
+			want:    map[string]any{"customers/c1/customerGroup": nil, "customers/c1/updatedAt": created},
+		},
+		{
+			name:    "earlier order removed",
+			group:   "GROUP_LOYAL",
+			removed: []string{"o1"},
+			want:    map[string]any{"customers/c1/customerGroup": nil},
+		},
+		{
+			name:    "every order removed",
+			group:   "GROUP_LOYAL",
+			removed: []string{"o1", "o2"},
+			want:    map[string]any{"customers/c1/customerGroup": nil, "customers/c1/updatedAt": created},
+		},
+		{
+			name:    "lapsed customer with orders left stays lapsed",
+			group:   "GROUP_LAPSED",
+			removed: []string{"o1"},
+			want:    map[string]any{},
+		},
+		{
+			name:    "lapsed customer without orders left",
+			group:   "GROUP_LAPSED",
+			removed: []string{"o1", "o2"},
+			want:    map[string]any{"customers/c1/customerGroup": nil, "customers/c1/updatedAt": created},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := purgeTree()
+			customer := tree["customers"].(map[string]any)["c1"].(map[string]any)
+			customer["customerGroup"] = test.group
+			var items []PurgeItem
+			for _, key := range test.removed {
+				items = append(items, PurgeItem{Collection: "orders", Key: key})
+			}
+			update, keys := customerRecomputes(tree, items)
+			if !reflect.DeepEqual(update, test.want) {
+				t.Errorf("update = %v, want %v", update, test.want)
+			}
+			if !reflect.DeepEqual(keys, []string{"c1"}) {
+				t.Errorf("keys = %v, want [c1]", keys)
+			}
+		})
+	}
+
+	// Removing the customer along with the orders leaves nothing to recompute.
+	update, keys := customerRecomputes(purgeTree(), []PurgeItem{
+		{Collection: "orders", Key: "o2"},
+		{Collection: "customers", Key: "c1"},
+	})
+	if len(update) != 0 || len(keys) != 0 {
+		t.Errorf("customerRecomputes = %v, %v, want nothing", update, keys)
+	}
+}
+
+func TestPurgeUpdate(t *testing.T) {
+	tree := purgeTree()
+	items := []PurgeItem{
+		{Collection: "orders", Key: "o1"},
+		{Collection: "refunds", Key: "r1"},
+		{Collection: "finance/transactions", Key: "t1"},
+	}
+	want := map[string]any{
+		"orders/o1":               nil,
+		"refunds":                 nil,
+		"finance/transactions/t1": nil,
+	}
+	if got := purgeUpdate(tree, items); !reflect.DeepEqual(got, want) {
+		t.Errorf("purgeUpdate = %v, want %v", got, want)
+	}
+}
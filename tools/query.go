@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Query is the ordering and filtering of a read: orderBy with optional
+// startAt, endAt, equalTo and a limit. Bounds are JSON values compared the
+// way the database orders them.
+type Query struct {
+	// OrderBy is $key, $value or the path of a child; $priority only comes
+	// from the wire protocol.
+	OrderBy      string
+	StartAt      any
+	EndAt        any
+	EqualTo      any
+	LimitToFirst int
+	LimitToLast  int
+
+	hasStart, hasEnd, hasEqual bool
+	// The client SDKs also bound by key among equal values, and exclude a
+	// bound for startAfter and endBefore.
+	startName, endName           string
+	startExclusive, endExclusive bool
+}
+
+// minName and maxName are the key bounds before and after every key.
+const (
+	minName = "[MIN_NAME]"
+	maxName = "[MAX_NAME]"
+)
+
+// parseQuery reads the query parameters of a request, or returns nil when it
+// has none.
+func parseQuery(values url.Values) (*Query, error) {
+	var q Query
+	if raw := values.Get("orderBy"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &q.OrderBy); err != nil {
+			return nil, fmt.Errorf("orderBy must be a valid JSON encoded path")
+		}
+		if q.OrderBy == "$priority" {
+			return nil, fmt.Errorf("orderBy $priority is not supported")
+		}
+	}
+
+	bounds := []struct {
+		name  string
+		value *any
+		set   *bool
+	}{
+		{"startAt", &q.StartAt, &q.hasStart},
+		{"endAt", &q.EndAt, &q.hasEnd},
+		{"equalTo", &q.EqualTo, &q.hasEqual},
+	}
+	for _, bound := range bounds {
+		raw, ok := values[bound.name]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal([]byte(raw[0]), bound.value); err != nil {
+			return nil, fmt.Errorf("%s must be a valid JSON value", bound.name)
+		}
+		if _, isObject := (*bound.value).(map[string]any); isObject {
+			return nil, fmt.Errorf("%s must be a primitive", bound.name)
+		}
+		*bound.set = true
+	}
+
+	limits := []struct {
+		name  string
+		value *int
+	}{
+		{"limitToFirst", &q.LimitToFirst},
+		{"limitToLast", &q.LimitToLast},
+	}
+	for _, limit := range limits {
+		raw := values.Get(limit.name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%s must be a positive integer", limit.name)
+		}
+		*limit.value = n
+	}
+
+	filtered := q.hasStart || q.hasEnd || q.hasEqual || q.LimitToFirst > 0 || q.LimitToLast > 0
+	switch {
+	case q.OrderBy == "" && filtered:
+		return nil, fmt.Errorf("orderBy must be defined when other query parameters are defined")
+	case q.OrderBy == "":
+		return nil, nil
+	case q.hasEqual && (q.hasStart || q.hasEnd):
+		return nil, fmt.Errorf("equalTo cannot be specified in addition to startAt or endAt")
+	case q.LimitToFirst > 0 && q.LimitToLast > 0:
+		return nil, fmt.Errorf("limitToFirst and limitToLast cannot both be specified")
+	case q.OrderBy == "$key" && !(stringOrUnset(q.StartAt) && stringOrUnset(q.EndAt) && stringOrUnset(q.EqualTo)):
+		return nil, fmt.Errorf("when orderBy is $key, startAt, endAt and equalTo must be strings")
+	}
+	return &q, nil
+}
+
+// parseWireQuery reads the query object of a wire protocol listen or get:
+// i names the index, sp, sn and sin the start value, key and whether it is
+// included, ep, en and ein the end, l the limit and vf whether it counts from
+// the left or the right. It returns nil for a query that reads everything.
+func parseWireQuery(object map[string]any) (*Query, error) {
+	var q Query
+	switch index, _ := object["i"].(string); index {
+	case "", ".priority":
+		q.OrderBy = "$priority"
+	case ".key":
+		q.OrderBy = "$key"
+	case ".value":
+		q.OrderBy = "$value"
+	default:
+		q.OrderBy = strings.Trim(index, "/")
+	}
+
+	if value, ok := object["sp"]; ok {
+		q.StartAt, q.hasStart = value, true
+		q.startName, _ = object["sn"].(string)
+		q.startExclusive = object["sin"] == false
+	}
+	if value, ok := object["ep"]; ok {
+		q.EndAt, q.hasEnd = value, true
+		q.endName, _ = object["en"].(string)
+		q.endExclusive = object["ein"] == false
+	}
+	for _, bound := range []any{q.StartAt, q.EndAt} {
+		if _, isObject := bound.(map[string]any); isObject {
+			return nil, fmt.Errorf("query bounds must be primitives")
+		}
+	}
+	if value, ok := object["l"]; ok {
+		limit, _ := value.(float64)
+		if limit < 1 || limit != float64(int(limit)) {
+			return nil, fmt.Errorf("query limit must be a positive integer")
+		}
+		if object["vf"] == "r" {
+			q.LimitToLast = int(limit)
+		} else {
+			q.LimitToFirst = int(limit)
+		}
+	}
+	if !q.hasStart && !q.hasEnd && q.LimitToFirst == 0 && q.LimitToLast == 0 {
+		return nil, nil
+	}
+	return &q, nil
+}
+
+func stringOrUnset(value any) bool {
+	_, ok := value.(string)
+	return ok || value == nil
+}
+
+// Apply returns the children of node that match the query. Like the REST API,
+// the result is an object and carries no order.
+func (q *Query) Apply(node any) any {
+	children, ok := node.(map[string]any)
+	if !ok {
+		return node
+	}
+
+	type entry struct {
+		key   string
+		value any
+	}
+	entries := make([]entry, 0, len(children))
+	for key, child := range children {
+		entries = append(entries, entry{key, q.sortValue(key, child)})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if c := q.compare(entries[i].value, entries[j].value); c != 0 {
+			return c < 0
+		}
+		return compareKeys(entries[i].key, entries[j].key) < 0
+	})
+
+	matched := entries[:0]
+	for _, e := range entries {
+		if q.hasEqual && q.compare(e.value, q.EqualTo) != 0 {
+			continue
+		}
+		if c := q.position(e.key, e.value, q.StartAt, q.startName); q.hasStart && (c < 0 || c == 0 && q.startExclusive) {
+			continue
+		}
+		if c := q.position(e.key, e.value, q.EndAt, q.endName); q.hasEnd && (c > 0 || c == 0 && q.endExclusive) {
+			continue
+		}
+		matched = append(matched, e)
+	}
+	if q.LimitToFirst > 0 && len(matched) > q.LimitToFirst {
+		matched = matched[:q.LimitToFirst]
+	}
+	if q.LimitToLast > 0 && len(matched) > q.LimitToLast {
+		matched = matched[len(matched)-q.LimitToLast:]
+	}
+
+	result := make(map[string]any, len(matched))
+	for _, e := range matched {
+		result[e.key] = children[e.key]
+	}
+	return result
+}
+
+func (q *Query) sortValue(key string, child any) any {
+	switch q.OrderBy {
+	case "$key":
+		return key
+	case "$value":
+		return child
+	case "$priority":
+		// Priorities are not stored: every child has none.
+		return nil
+	}
+	return getAt(child, splitPath(q.OrderBy))
+}
+
+// position places a child against a bound: by its sort value, then, when
+// the bound names a key, by its key.
+func (q *Query) position(key string, value, bound any, name string) int {
+	if c := q.compare(value, bound); c != 0 || name == "" {
+		return c
+	}
+	switch name {
+	case minName:
+		return 1
+	case maxName:
+		return -1
+	}
+	return compareKeys(key, name)
+}
+
+func (q *Query) compare(a, b any) int {
+	if q.OrderBy == "$key" {
+		as, _ := a.(string)
+		bs, _ := b.(string)
+		return compareKeys(as, bs)
+	}
+	return compareValues(a, b)
+}
+
+// compareValues orders values as the database does: null, false, true,
+// numbers, strings, then objects.
+func compareValues(a, b any) int {
+	if ra, rb := valueRank(a), valueRank(b); ra != rb {
+		return ra - rb
+	}
+	switch av := a.(type) {
+	case float64:
+		bv := b.(float64)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+	case string:
+		return strings.Compare(av, b.(string))
+	}
+	return 0
+}
+
+func valueRank(value any) int {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 2
+		}
+		return 1
+	case float64:
+		return 3
+	case string:
+		return 4
+	}
+	return 5
+}
+
+// compareKeys orders keys as the database does: keys that are 32-bit integers
+// first, numerically, then the rest as strings.
+func compareKeys(a, b string) int {
+	ai, aInt := intKey(a)
+	bi, bInt := intKey(b)
+	switch {
+	case aInt && bInt:
+		return int(ai - bi)
+	case aInt:
+		return -1
+	case bInt:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// intKey parses a key written as a 32-bit integer, without leading zeros.
+func intKey(key string) (int64, bool) {
+	n, err := strconv.ParseInt(key, 10, 32)
+	return n, err == nil && strconv.FormatInt(n, 10) == key
+}
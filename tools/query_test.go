@@ -0,0 +1,166 @@
+package main
+
+import (
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestQueryApply(t *testing.T) {
+	orders := map[string]any{
+		"ORD_001": map[string]any{"status": "done", "total": float64(300)},
+		"ORD_002": map[string]any{"status": "pending", "total": float64(100)},
+		"ORD_003": map[string]any{"status": "pending", "total": float64(200)},
+		"ORD_004": map[string]any{"status": "done"},
+		"ORD_005": map[string]any{"status": "pending", "total": float64(100)},
+	}
+	scores := map[string]any{"a": float64(3), "b": "x", "c": true, "d": float64(-1), "10": false, "9": float64(3)}
+
+	tests := []struct {
+		name    string
+		params  string
+		node    map[string]any
+		want    []string
+		wantErr string
+	}{
+		{name: "no query", params: ``, node: orders, want: []string{"ORD_001", "ORD_002", "ORD_003", "ORD_004", "ORD_005"}},
+		{name: "limitToFirst by child", params: `orderBy="total"&limitToFirst=2`, node: orders, want: []string{"ORD_002", "ORD_004"}},
+		{name: "limitToLast by child", params: `orderBy="total"&limitToLast=2`, node: orders, want: []string{"ORD_001", "ORD_003"}},
+		{name: "ties ordered by key", params: `orderBy="total"&startAt=100&limitToFirst=2`, node: orders, want: []string{"ORD_002", "ORD_005"}},
+		{name: "missing child sorts first", params: `orderBy="total"&endAt=null`, node: orders, want: []string{"ORD_004"}},
+		{name: "startAt and endAt", params: `orderBy="total"&startAt=150&endAt=300`, node: orders, want: []string{"ORD_001", "ORD_003"}},
+		{name: "equalTo", params: `orderBy="status"&equalTo="pending"`, node: orders, want: []string{"ORD_002", "ORD_003", "ORD_005"}},
+		{name: "equalTo with limitToLast", params: `orderBy="status"&equalTo="pending"&limitToLast=1`, node: orders, want: []string{"ORD_005"}},
+		{name: "$key range", params: `orderBy="$key"&startAt="ORD_002"&endAt="ORD_003"`, node: orders, want: []string{"ORD_002", "ORD_003"}},
+		{name: "$key puts integer keys first", params: `orderBy="$key"&limitToFirst=2`, node: scores, want: []string{"10", "9"}},
+		{name: "$value orders by type", params: `orderBy="$value"&limitToFirst=3`, node: scores, want: []string{"10", "c", "d"}},
+		{name: "$value strings last", params: `orderBy="$value"&limitToLast=1`, node: scores, want: []string{"b"}},
+		{name: "$value numbers", params: `orderBy="$value"&startAt=0&endAt=10`, node: scores, want: []string{"9", "a"}},
+		{name: "filter without orderBy", params: `limitToFirst=1`, wantErr: "orderBy must be defined"},
+		{name: "equalTo with startAt", params: `orderBy="total"&equalTo=1&startAt=1`, wantErr: "equalTo cannot be specified"},
+		{name: "both limits", params: `orderBy="total"&limitToFirst=1&limitToLast=1`, wantErr: "cannot both be specified"},
+		{name: "$key with number bound", params: `orderBy="$key"&startAt=1`, wantErr: "must be strings"},
+		{name: "zero limit", params: `orderBy="total"&limitToFirst=0`, wantErr: "positive integer"},
+		{name: "object bound", params: `orderBy="total"&startAt={"a":1}`, wantErr: "must be a primitive"},
+		{name: "unquoted orderBy", params: `orderBy=total`, wantErr: "valid JSON encoded path"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := url.ParseQuery(test.params)
+			if err != nil {
+				t.Fatalf("parse %q: %v", test.params, err)
+			}
+			query, err := parseQuery(values)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("parseQuery error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseQuery: %v", err)
+			}
+			var result any = test.node
+			if query != nil {
+				result = query.Apply(test.node)
+			}
+			if got := sortedKeys(result); !slices.Equal(got, test.want) {
+				t.Errorf("keys = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseWireQuery(t *testing.T) {
+	// As the client SDKs send them: children ordered by total, ties by key.
+	orders := map[string]any{
+		"a": map[string]any{"total": float64(100)},
+		"b": map[string]any{"total": float64(200)},
+		"c": map[string]any{"total": float64(200)},
+		"d": map[string]any{"total": float64(300)},
+	}
+
+	tests := []struct {
+		name    string
+		object  map[string]any
+		want    []string
+		wantErr string
+	}{
+		{name: "everything", object: map[string]any{}, want: nil},
+		{name: "order without bounds", object: map[string]any{"i": "total"}, want: nil},
+		{name: "limitToLast", object: map[string]any{"i": "total", "l": float64(2), "vf": "r"}, want: []string{"c", "d"}},
+		{name: "limitToFirst", object: map[string]any{"i": "total", "l": float64(1), "vf": "l"}, want: []string{"a"}},
+		{name: "priority index orders by key", object: map[string]any{"l": float64(2), "vf": "r"}, want: []string{"c", "d"}},
+		{
+			name:   "startAt value takes every key",
+			object: map[string]any{"i": "total", "sp": float64(200), "sn": minName},
+			want:   []string{"b", "c", "d"},
+		},
+		{
+			name:   "startAfter value skips every key",
+			object: map[string]any{"i": "total", "sp": float64(200), "sn": maxName, "sin": false},
+			want:   []string{"d"},
+		},
+		{
+			name:   "startAt value and key",
+			object: map[string]any{"i": "total", "sp": float64(200), "sn": "c"},
+			want:   []string{"c", "d"},
+		},
+		{
+			name:   "equalTo",
+			object: map[string]any{"i": "total", "sp": float64(200), "sn": minName, "ep": float64(200), "en": maxName},
+			want:   []string{"b", "c"},
+		},
+		{
+			name:   "endBefore value",
+			object: map[string]any{"i": "total", "ep": float64(200), "en": minName, "ein": false},
+			want:   []string{"a"},
+		},
+		{
+			name:   "$key range",
+			object: map[string]any{"i": ".key", "sp": "b", "sn": minName, "ep": "c", "en": maxName},
+			want:   []string{"b", "c"},
+		},
+		{name: "fractional limit", object: map[string]any{"i": "total", "l": 1.5}, wantErr: "positive integer"},
+		{name: "object bound", object: map[string]any{"i": "total", "sp": map[string]any{}}, wantErr: "primitives"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, err := parseWireQuery(test.object)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("parseWireQuery error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseWireQuery: %v", err)
+			}
+			if test.want == nil {
+				if query != nil {
+					t.Fatalf("query = %+v, want nil", query)
+				}
+				return
+			}
+			if query == nil {
+				t.Fatalf("query = nil, want %v", test.want)
+			}
+			if got := sortedKeys(query.Apply(orders)); !slices.Equal(got, test.want) {
+				t.Errorf("keys = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// sortedKeys returns the keys of an object, sorted as strings.
+func sortedKeys(value any) []string {
+	children, _ := value.(map[string]any)
+	keys := make([]string, 0, len(children))
+	for key := range children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
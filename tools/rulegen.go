@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// RuleSpec is the permission matrix the rules command turns into a rules
+// file: who may read and write each collection and what its records must
+// look like. Field names are checked against the generator's types.
+type RuleSpec struct {
+	Root string `json:"root"`
+	// FullAccess roles read and write the whole tree, like the "all" roles of
+	// src/configs/role.ts.
+	FullAccess  []string                  `json:"fullAccess"`
+	Collections map[string]CollectionSpec `json:"collections"`
+}
+
+// CollectionSpec grants roles access to one collection, keyed by its path
+// under the root. A collection granting no role is left to the full-access
+// roles; the spec still lists it so that every collection the app uses is
+// accounted for.
+type CollectionSpec struct {
+	// Key names the wildcard of the collection's records (default $id).
+	Key   string   `json:"key,omitempty"`
+	Read  []string `json:"read,omitempty"`
+	Write []string `json:"write,omitempty"`
+	// WriteIf limits the write roles to records whose fields hold these
+	// values, e.g. the order income entries sales record in finance.
+	WriteIf map[string]string `json:"writeIf,omitempty"`
+	// Required fields must be present on every record written.
+	Required []string `json:"required,omitempty"`
+	// Enums maps a field to its allowed values, or to the name of one of the
+	// generator's lists in ruleEnums.
+	Enums   map[string]json.RawMessage `json:"enums,omitempty"`
+	IndexOn []string                   `json:"indexOn,omitempty"`
+	// Paths lets more roles write below a record, keyed by a path relative to
+	// the record, e.g. products/$productId/workflows/$workflowId.
+	Paths map[string]PathSpec `json:"paths,omitempty"`
+}
+
+// PathSpec grants write access below a record.
+type PathSpec struct {
+	Write []string `json:"write"`
+}
+
+// ruleEnums are the generator's enum lists a spec can refer to by name; they
+// follow the enums of the app.
+var ruleEnums = map[string]*[]string{
+	"roles":                   &roles,
+	"genders":                 &genders,
+	"customerSources":         &customerSources,
+	"warrantyStatuses":        &warrantyStatuses,
+	"refundStatuses":          &refundStatuses,
+	"refundTypes":             &refundTypes,
+	"discountTypes":           &discountTypes,
+	"feedbackTypes":           &feedbackTypes,
+	"financeTypes":            &financeTypes,
+	"financeCategories":       &financeCategories,
+	"paymentMethods":          &paymentMethods,
+	"operationalItemStatuses": &operationalItemStatuses,
+	"standaloneTaskStatuses":  &standaloneTaskStatuses,
+	"standaloneTaskTypes":     &standaloneTaskTypes,
+}
+
+// ruleObject is a JSON object that keeps its keys in the order they were set,
+// so rules come out in reading order rather than sorted.
+type ruleObject struct {
+	keys   []string
+	values map[string]any
+}
+
+func newRuleObject() *ruleObject {
+	return &ruleObject{values: make(map[string]any)}
+}
+
+func (o *ruleObject) set(key string, value any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// child returns the object at a slash-separated path, creating it as needed.
+func (o *ruleObject) child(path string) *ruleObject {
+	node := o
+	for _, segment := range splitPath(path) {
+		next, ok := node.values[segment].(*ruleObject)
+		if !ok {
+			next = newRuleObject()
+			node.set(segment, next)
+		}
+		node = next
+	}
+	return node
+}
+
+func (o *ruleObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := marshalRuleJSON(key)
+		value, err := marshalRuleJSON(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// marshalRuleJSON encodes without escaping &, < and >, which rules use as
+// operators.
+func marshalRuleJSON(value any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// collectionSchemas maps the path of every collection the generator writes,
+// relative to the root, to the type of its records.
+func collectionSchemas() map[string]reflect.Type {
+	schemas := make(map[string]reflect.Type)
+	var walk func(t reflect.Type, prefix string)
+	walk = func(t reflect.Type, prefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := prefix + jsonName(field)
+			switch {
+			case field.Type.Kind() == reflect.Map:
+				schemas[name] = field.Type.Elem()
+			case field.Type.Kind() == reflect.Struct && field.Type.Name() == "":
+				walk(field.Type, name+"/")
+			}
+		}
+	}
+	root, _ := reflect.TypeOf(MockData{}).FieldByName("Xoxo")
+	walk(root.Type, "")
+	return schemas
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// schemaField returns the type of a slash-separated field path in a record type.
+func schemaField(t reflect.Type, path string) (reflect.Type, bool) {
+	for _, segment := range splitPath(path) {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			found := false
+			for i := 0; i < t.NumField(); i++ {
+				if jsonName(t.Field(i)) == segment {
+					t, found = t.Field(i).Type, true
+					break
+				}
+			}
+			if !found {
+				return nil, false
+			}
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return nil, false
+		}
+	}
+	return t, true
+}
+
+// typeCheck is the .validate condition for a field of a Go type.
+func typeCheck(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "newData.isString()"
+	case reflect.Bool:
+		return "newData.isBoolean()"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "newData.isNumber()"
+	}
+	return "newData.hasChildren()"
+}
+
+func quoteRuleString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// roleCondition grants signed-in users whose role claim is one of roles.
+func roleCondition(roles []string) string {
+	checks := make([]string, len(roles))
+	for i, role := range roles {
+		checks[i] = "auth.token.role == " + quoteRuleString(role)
+	}
+	if len(checks) == 1 {
+		return "auth != null && " + checks[0]
+	}
+	return "auth != null && (" + strings.Join(checks, " || ") + ")"
+}
+
+func (c CollectionSpec) enumValues(field string) ([]string, error) {
+	var values []string
+	if err := json.Unmarshal(c.Enums[field], &values); err == nil {
+		return values, nil
+	}
+	var name string
+	if err := json.Unmarshal(c.Enums[field], &name); err != nil {
+		return nil, fmt.Errorf("enum %s must be a list of values or the name of a list", field)
+	}
+	list, ok := ruleEnums[name]
+	if !ok {
+		return nil, fmt.Errorf("enum %s refers to unknown list %q", field, name)
+	}
+	return *list, nil
+}
+
+// generateRules builds the rules file for a spec. Full-access roles are
+// granted at the root; every collection then grants reads on itself and
+// writes on its records, validates required fields, field types and enum
+// values, and indexes the queried fields.
+func generateRules(spec RuleSpec) (*ruleObject, error) {
+	checkRoles := func(where string, list []string) error {
+		for _, role := range list {
+			if !slices.Contains(roles, role) {
+				return fmt.Errorf("%s: unknown role %q", where, role)
+			}
+		}
+		return nil
+	}
+	if err := checkRoles("fullAccess", spec.FullAccess); err != nil {
+		return nil, err
+	}
+
+	file := newRuleObject()
+	rules := newRuleObject()
+	file.set("rules", rules)
+	root := rules.child(spec.Root)
+	if len(spec.FullAccess) > 0 {
+		root.set(".read", roleCondition(spec.FullAccess))
+		root.set(".write", roleCondition(spec.FullAccess))
+	}
+
+	schemas := collectionSchemas()
+	names := make([]string, 0, len(spec.Collections))
+	for name := range spec.Collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		collection := spec.Collections[name]
+		if err := checkRoles(name+".read", collection.Read); err != nil {
+			return nil, err
+		}
+		if err := checkRoles(name+".write", collection.Write); err != nil {
+			return nil, err
+		}
+
+		// Fields that get a .validate rule: everything the spec names.
+		fields := append(append([]string(nil), collection.Required...), collection.IndexOn...)
+		for field := range collection.Enums {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		fields = slices.Compact(fields)
+		schema, hasSchema := schemas[name]
+		if len(fields) > 0 && !hasSchema {
+			return nil, fmt.Errorf("%s: fields are named but the generator has no schema for it", name)
+		}
+		fieldTypes := make(map[string]reflect.Type, len(fields))
+		for _, field := range fields {
+			t, ok := schemaField(schema, field)
+			if !ok {
+				return nil, fmt.Errorf("%s: %s has no field %s", name, schema.Name(), field)
+			}
+			fieldTypes[field] = t
+		}
+
+		node := root.child(name)
+		if len(collection.Read) > 0 {
+			node.set(".read", roleCondition(collection.Read))
+		}
+		if len(collection.IndexOn) > 0 {
+			node.set(".indexOn", collection.IndexOn)
+		}
+
+		key := collection.Key
+		if key == "" {
+			key = "$id"
+		}
+		if !strings.HasPrefix(key, "$") {
+			return nil, fmt.Errorf("%s: key %q must start with $", name, key)
+		}
+		record := func() *ruleObject { return node.child(key) }
+		if len(collection.WriteIf) > 0 && len(collection.Write) == 0 {
+			return nil, fmt.Errorf("%s: writeIf needs write roles", name)
+		}
+		if len(collection.Write) > 0 {
+			write := roleCondition(collection.Write)
+			conditions := make([]string, 0, len(collection.WriteIf))
+			for field := range collection.WriteIf {
+				conditions = append(conditions, field)
+			}
+			sort.Strings(conditions)
+			for _, field := range conditions {
+				if !hasSchema {
+					return nil, fmt.Errorf("%s: writeIf names fields but the generator has no schema for it", name)
+				}
+				if _, ok := schemaField(schema, field); !ok {
+					return nil, fmt.Errorf("%s: %s has no field %s", name, schema.Name(), field)
+				}
+				write += " && newData.child(" + quoteRuleString(field) + ").val() == " + quoteRuleString(collection.WriteIf[field])
+			}
+			record().set(".write", write)
+		}
+		if len(collection.Required) > 0 {
+			quoted := make([]string, len(collection.Required))
+			for i, field := range collection.Required {
+				quoted[i] = quoteRuleString(field)
+			}
+			record().set(".validate", "newData.hasChildren(["+strings.Join(quoted, ", ")+"])")
+		}
+		for _, field := range fields {
+			check := typeCheck(fieldTypes[field])
+			if _, ok := collection.Enums[field]; ok {
+				values, err := collection.enumValues(field)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+				allowed := make([]string, len(values))
+				for i, value := range values {
+					allowed[i] = "newData.val() == " + quoteRuleString(value)
+				}
+				check += " && (" + strings.Join(allowed, " || ") + ")"
+			}
+			record().child(field).set(".validate", check)
+		}
+
+		paths := make([]string, 0, len(collection.Paths))
+		for path := range collection.Paths {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			if err := checkRoles(name+"/"+path, collection.Paths[path].Write); err != nil {
+				return nil, err
+			}
+			record().child(path).set(".write", roleCondition(collection.Paths[path].Write))
+		}
+	}
+	return file, nil
+}
+
+// rulesCommand runs `rules [flags] [firebase.rule.generated.json]`.
+func rulesCommand(args []string) error {
+	flags := flag.NewFlagSet("rules", flag.ExitOnError)
+	specPath := flags.String("spec", "firebase.rule.spec.json", "permission matrix to generate the rules from")
+	flags.Parse(args)
+
+	outputFile := "firebase.rule.generated.json"
+	if flags.NArg() > 0 {
+		outputFile = flags.Arg(0)
+	}
+
+	raw, err := os.ReadFile(*specPath)
+	if err != nil {
+		return err
+	}
+	var spec RuleSpec
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return fmt.Errorf("parse %s: %w", *specPath, err)
+	}
+	if spec.Root == "" {
+		spec.Root = "xoxo"
+	}
+	rules, err := generateRules(spec)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *specPath, err)
+	}
+	compact, err := marshalRuleJSON(rules)
+	if err != nil {
+		return err
+	}
+	var jsonData bytes.Buffer
+	if err := json.Indent(&jsonData, compact, "", "  "); err != nil {
+		return err
+	}
+	jsonData.WriteByte('\n')
+	if err := os.WriteFile(outputFile, jsonData.Bytes(), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote rules for %d collections to %s\n", len(spec.Collections), outputFile)
+	return nil
+}
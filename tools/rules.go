@@ -0,0 +1,374 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RuleNode is the rules for one level of the tree: its .read, .write and
+// .validate expressions, the rules of named children and at most one
+// $wildcard child for the rest.
+type RuleNode struct {
+	Read     ruleExpr
+	Write    ruleExpr
+	Validate ruleExpr
+	IndexOn  []string
+
+	Children map[string]*RuleNode
+	Wildcard string
+	Matched  *RuleNode
+
+	// source keeps the expressions' text for decisions.
+	source map[string]string
+}
+
+// loadRules reads a firebase.rule.json style file.
+func loadRules(path string) (*RuleNode, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Rules map[string]any `json:"rules"`
+	}
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if file.Rules == nil {
+		return nil, fmt.Errorf("%s has no \"rules\" object", path)
+	}
+	rules, err := compileRules(file.Rules, "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+func compileRules(spec map[string]any, at string) (*RuleNode, error) {
+	node := &RuleNode{Children: make(map[string]*RuleNode), source: make(map[string]string)}
+	for key, value := range spec {
+		switch {
+		case key == ".read" || key == ".write" || key == ".validate":
+			var src string
+			switch v := value.(type) {
+			case bool:
+				src = fmt.Sprint(v)
+			case string:
+				src = v
+			default:
+				return nil, fmt.Errorf("%s/%s must be a string or a boolean", at, key)
+			}
+			expr, err := parseRuleExpr(src)
+			if err != nil {
+				return nil, fmt.Errorf("%s/%s: %w", at, key, err)
+			}
+			node.source[key] = src
+			switch key {
+			case ".read":
+				node.Read = expr
+			case ".write":
+				node.Write = expr
+			default:
+				node.Validate = expr
+			}
+		case key == ".indexOn":
+			switch v := value.(type) {
+			case string:
+				node.IndexOn = []string{v}
+			case []any:
+				for _, field := range v {
+					name, ok := field.(string)
+					if !ok {
+						return nil, fmt.Errorf("%s/.indexOn must list strings", at)
+					}
+					node.IndexOn = append(node.IndexOn, name)
+				}
+			default:
+				return nil, fmt.Errorf("%s/.indexOn must be a string or a list of strings", at)
+			}
+		case strings.HasPrefix(key, "."):
+			return nil, fmt.Errorf("%s/%s is not a known rule", at, key)
+		default:
+			childSpec, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s/%s must be an object", at, key)
+			}
+			child, err := compileRules(childSpec, at+"/"+key)
+			if err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(key, "$") {
+				if node.Matched != nil {
+					return nil, fmt.Errorf("%s has two wildcards, %s and %s", at, node.Wildcard, key)
+				}
+				node.Wildcard, node.Matched = key, child
+				continue
+			}
+			node.Children[key] = child
+		}
+	}
+	return node, nil
+}
+
+// child returns the rules for a child key and binds the wildcard it matched,
+// or nil when no rule applies below this level.
+func (n *RuleNode) child(key string, env ruleEnv) *RuleNode {
+	if n == nil {
+		return nil
+	}
+	if child, ok := n.Children[key]; ok {
+		return child
+	}
+	if n.Matched != nil {
+		env[n.Wildcard] = key
+	}
+	return n.Matched
+}
+
+// Decision is the outcome of checking an operation, with the rule that
+// decided it.
+type Decision struct {
+	Allowed bool
+	Reason  string
+}
+
+// RuleChecker checks operations by one user against a tree with a rule set.
+type RuleChecker struct {
+	rules *RuleNode
+	root  any
+	auth  map[string]any
+	now   int64
+}
+
+func (c *RuleChecker) env(newRoot any) ruleEnv {
+	var auth any
+	if c.auth != nil {
+		auth = c.auth
+	}
+	return ruleEnv{
+		"auth":    auth,
+		"now":     float64(c.now),
+		"root":    ruleSnapshot{c.root, nil},
+		"data":    ruleSnapshot{c.root, nil},
+		"newData": ruleSnapshot{newRoot, nil},
+	}
+}
+
+// holds evaluates a rule at a location; errors and non-boolean results deny.
+func holds(expr ruleExpr, env ruleEnv) (bool, error) {
+	value, err := evalRule(expr, env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("rule returned %T, not a boolean", value)
+	}
+	return b, nil
+}
+
+// at points data and newData at path.
+func at(env ruleEnv, path []string) {
+	env["data"] = ruleSnapshot{env["data"].(ruleSnapshot).root, path}
+	env["newData"] = ruleSnapshot{env["newData"].(ruleSnapshot).root, path}
+}
+
+// cascade reports whether a .read or .write rule on the way from the root to
+// path grants access; a grant at any level covers everything below it. A rule
+// that fails to evaluate counts as false, as in Firebase, and the rules below
+// it are still checked.
+func (c *RuleChecker) cascade(kind string, path []string, newRoot any) Decision {
+	env := c.env(newRoot)
+	node := c.rules
+	var failed []string
+	for depth := 0; depth <= len(path) && node != nil; depth++ {
+		if depth > 0 {
+			node = node.child(path[depth-1], env)
+			if node == nil {
+				break
+			}
+		}
+		expr := node.Read
+		if kind == ".write" {
+			expr = node.Write
+		}
+		if expr == nil {
+			continue
+		}
+		at(env, path[:depth])
+		ok, err := holds(expr, env)
+		if ok {
+			return Decision{true, fmt.Sprintf("%s at /%s: %s", kind, strings.Join(path[:depth], "/"), node.source[kind])}
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s at /%s failed: %v", kind, strings.Join(path[:depth], "/"), err))
+		}
+	}
+	reason := fmt.Sprintf("no %s rule grants /%s", kind, strings.Join(path, "/"))
+	if len(failed) > 0 {
+		reason += " (" + strings.Join(failed, "; ") + ")"
+	}
+	return Decision{false, reason}
+}
+
+// validate runs the .validate rules of every location the write leaves data
+// at: the ancestors of path and everything stored at or under it.
+func (c *RuleChecker) validate(path []string, newRoot any) Decision {
+	env := c.env(newRoot)
+	node := c.rules
+	for depth := 0; depth < len(path) && node != nil; depth++ {
+		if depth > 0 {
+			node = node.child(path[depth-1], env)
+		}
+		if node == nil || node.Validate == nil || getAt(newRoot, path[:depth]) == nil {
+			continue
+		}
+		if decision := c.validateAt(node, env, path[:depth]); !decision.Allowed {
+			return decision
+		}
+	}
+	if len(path) > 0 {
+		node = node.child(path[len(path)-1], env)
+	}
+	return c.validateTree(node, env, path, newRoot)
+}
+
+func (c *RuleChecker) validateAt(node *RuleNode, env ruleEnv, path []string) Decision {
+	at(env, path)
+	ok, err := holds(node.Validate, env)
+	if ok {
+		return Decision{Allowed: true}
+	}
+	reason := fmt.Sprintf(".validate at /%s: %s", strings.Join(path, "/"), node.source[".validate"])
+	if err != nil {
+		reason += fmt.Sprintf(" (%v)", err)
+	}
+	return Decision{false, reason}
+}
+
+func (c *RuleChecker) validateTree(node *RuleNode, env ruleEnv, path []string, newRoot any) Decision {
+	value := getAt(newRoot, path)
+	if node == nil || value == nil {
+		return Decision{Allowed: true}
+	}
+	if node.Validate != nil {
+		if decision := c.validateAt(node, env, path); !decision.Allowed {
+			return decision
+		}
+	}
+	children, ok := value.(map[string]any)
+	if !ok {
+		return Decision{Allowed: true}
+	}
+	keys := make([]string, 0, len(children))
+	for key := range children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		childEnv := make(ruleEnv, len(env))
+		for name, value := range env {
+			childEnv[name] = value
+		}
+		childPath := append(append([]string(nil), path...), key)
+		if decision := c.validateTree(node.child(key, childEnv), childEnv, childPath, newRoot); !decision.Allowed {
+			return decision
+		}
+	}
+	return Decision{Allowed: true}
+}
+
+// CheckRead decides whether the user may read path.
+func (c *RuleChecker) CheckRead(path []string) Decision {
+	return c.cascade(".read", path, c.root)
+}
+
+// CheckUpdate decides whether the user may write values, keyed by paths
+// relative to base, as one multi-path update. A set is an update of base
+// itself and a delete writes null.
+func (c *RuleChecker) CheckUpdate(base []string, values map[string]any) Decision {
+	if len(values) == 0 {
+		return Decision{false, "empty update"}
+	}
+	newRoot := c.root
+	paths := make([][]string, 0, len(values))
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		path := append(append([]string(nil), base...), splitPath(key)...)
+		value, err := normalizeValue(values[key], c.now)
+		if err != nil {
+			return Decision{false, err.Error()}
+		}
+		newRoot = withValue(newRoot, path, value)
+		paths = append(paths, path)
+	}
+	var granted Decision
+	for _, path := range paths {
+		if granted = c.cascade(".write", path, newRoot); !granted.Allowed {
+			return granted
+		}
+	}
+	for _, path := range paths {
+		if decision := c.validate(path, newRoot); !decision.Allowed {
+			return decision
+		}
+	}
+	return granted
+}
+
+// withValue returns a copy of root with value stored at path, sharing
+// everything off the path with root.
+func withValue(root any, path []string, value any) any {
+	if len(path) == 0 {
+		return value
+	}
+	copied := make(map[string]any)
+	if children, ok := root.(map[string]any); ok {
+		for key, child := range children {
+			copied[key] = child
+		}
+	}
+	child := withValue(copied[path[0]], path[1:], value)
+	if child == nil {
+		delete(copied, path[0])
+	} else {
+		copied[path[0]] = child
+	}
+	if len(copied) == 0 {
+		return nil
+	}
+	return copied
+}
+
+// authFor builds the auth variable of a member signed in with the account the
+// seed command creates: their loginAccount as UID and their role as a claim.
+func authFor(id string, member map[string]any) map[string]any {
+	uid, _ := member["loginAccount"].(string)
+	if uid == "" {
+		uid = id
+	}
+	email, _ := member["email"].(string)
+	return map[string]any{
+		"uid":      uid,
+		"provider": "password",
+		"token": map[string]any{
+			"sub":            uid,
+			"email":          email,
+			"email_verified": true,
+			"role":           member["role"],
+			"firebase":       map[string]any{"sign_in_provider": "password"},
+		},
+	}
+}
+
+func newRuleChecker(rules *RuleNode, root any, auth map[string]any) *RuleChecker {
+	return &RuleChecker{rules: rules, root: root, auth: auth, now: time.Now().UnixMilli()}
+}
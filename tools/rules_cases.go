@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// RuleCase is one row of a rules test table: who does what where, and
+// whether the rules should let them.
+type RuleCase struct {
+	Name string `json:"name"`
+	// Role signs in as the first active member with that role; with neither
+	// Role nor As the request is made signed out.
+	Role string `json:"role,omitempty"`
+	// As signs in as a particular member.
+	As string `json:"as,omitempty"`
+	// Op is read, write, update or delete. An update's data maps paths
+	// relative to Path to values.
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Allow bool            `json:"allow"`
+}
+
+// runRuleCase checks a case against the dataset and returns the decision.
+func runRuleCase(rules *RuleNode, root any, members map[string]any, tc RuleCase) (Decision, error) {
+	var auth map[string]any
+	switch {
+	case tc.As != "":
+		member, ok := members[tc.As].(map[string]any)
+		if !ok {
+			return Decision{}, fmt.Errorf("no member %s", tc.As)
+		}
+		auth = authFor(tc.As, member)
+	case tc.Role != "":
+		ids := make([]string, 0, len(members))
+		for id := range members {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			member, _ := members[id].(map[string]any)
+			if member["role"] == tc.Role && member["isActive"] == true {
+				auth = authFor(id, member)
+				break
+			}
+		}
+		if auth == nil {
+			return Decision{}, fmt.Errorf("no active member with role %s", tc.Role)
+		}
+	}
+
+	var data any
+	if len(tc.Data) > 0 {
+		if err := json.Unmarshal(tc.Data, &data); err != nil {
+			return Decision{}, fmt.Errorf("data: %w", err)
+		}
+	}
+
+	checker := newRuleChecker(rules, root, auth)
+	path := splitPath(tc.Path)
+	switch tc.Op {
+	case "read":
+		return checker.CheckRead(path), nil
+	case "write":
+		return checker.CheckUpdate(path, map[string]any{"": data}), nil
+	case "delete":
+		return checker.CheckUpdate(path, map[string]any{"": nil}), nil
+	case "update":
+		updates, ok := data.(map[string]any)
+		if !ok {
+			return Decision{}, fmt.Errorf("an update's data must be an object of paths")
+		}
+		return checker.CheckUpdate(path, updates), nil
+	}
+	return Decision{}, fmt.Errorf("unknown op %q", tc.Op)
+}
+
+// testRulesCommand runs `test-rules [flags] [mock-data.json]`: every case of
+// the table against the dataset, failing when any outcome differs.
+func testRulesCommand(args []string) error {
+	flags := flag.NewFlagSet("test-rules", flag.ExitOnError)
+	rulesPath := flags.String("rules", "firebase.rule.generated.json", "rules file to test")
+	casesPath := flags.String("cases", "firebase.rule.test.json", "JSON list of cases: name, role or as, op, path, data, allow")
+	membersPath := flags.String("members", "xoxo/members", "path of the members that roles are looked up in")
+	flags.Parse(args)
+
+	dataFile := "./mock-data.json"
+	if flags.NArg() > 0 {
+		dataFile = flags.Arg(0)
+	}
+
+	rules, err := loadRules(*rulesPath)
+	if err != nil {
+		return err
+	}
+	raw, err := os.ReadFile(*casesPath)
+	if err != nil {
+		return err
+	}
+	var cases []RuleCase
+	if err := json.Unmarshal(raw, &cases); err != nil {
+		return fmt.Errorf("parse %s: %w", *casesPath, err)
+	}
+	raw, err = os.ReadFile(dataFile)
+	if err != nil {
+		return err
+	}
+	var data any
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("parse %s: %w", dataFile, err)
+	}
+	root, err := normalizeValue(data, 0)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", dataFile, err)
+	}
+	members, _ := getAt(root, splitPath(*membersPath)).(map[string]any)
+
+	failed := 0
+	for i, tc := range cases {
+		name := tc.Name
+		if name == "" {
+			name = fmt.Sprintf("case %d", i+1)
+		}
+		decision, err := runRuleCase(rules, root, members, tc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if decision.Allowed == tc.Allow {
+			fmt.Printf("PASS  %s\n", name)
+			continue
+		}
+		failed++
+		want, got := "deny", "allow"
+		if tc.Allow {
+			want, got = got, want
+		}
+		fmt.Printf("FAIL  %s: want %s, got %s (%s)\n", name, want, got, decision.Reason)
+	}
+
+	fmt.Printf("%d passed, %d failed\n", len(cases)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d cases failed", failed, len(cases))
+	}
+	return nil
+}
@@ -0,0 +1,670 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The expression language of .read, .write and .validate rules: literals,
+// auth, now, root, data, newData and $wildcards, member access, snapshot and
+// string methods, and the JavaScript operators the rules language allows.
+
+type ruleExpr interface{}
+
+type (
+	literalExpr struct{ value any }
+	identExpr   struct{ name string }
+	memberExpr  struct {
+		object ruleExpr
+		name   string
+	}
+	indexExpr struct{ object, key ruleExpr }
+	callExpr  struct {
+		object ruleExpr
+		method string
+		args   []ruleExpr
+	}
+	unaryExpr struct {
+		op string
+		x  ruleExpr
+	}
+	binaryExpr struct {
+		op   string
+		x, y ruleExpr
+	}
+	ternaryExpr struct{ cond, then, otherwise ruleExpr }
+	arrayExpr   struct{ elems []ruleExpr }
+)
+
+type ruleToken struct {
+	kind  string // ident, number, string, regex, op, end
+	text  string
+	value any
+}
+
+// lexRule splits an expression into tokens. A slash starts a regular
+// expression literal where an operand is expected, and is division elsewhere.
+func lexRule(src string) ([]ruleToken, error) {
+	var tokens []ruleToken
+	operandExpected := true
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '_' || c == '$' || unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] == '$' || unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i]))) {
+				i++
+			}
+			tokens = append(tokens, ruleToken{kind: "ident", text: src[start:i]})
+			operandExpected = false
+		case unicode.IsDigit(rune(c)):
+			start := i
+			for i < len(src) && (unicode.IsDigit(rune(src[i])) || src[i] == '.') {
+				i++
+			}
+			n, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", src[start:i])
+			}
+			tokens = append(tokens, ruleToken{kind: "number", text: src[start:i], value: n})
+			operandExpected = false
+		case c == '\'' || c == '"':
+			var b strings.Builder
+			i++
+			for ; i < len(src) && src[i] != c; i++ {
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+				}
+				b.WriteByte(src[i])
+			}
+			if i == len(src) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			i++
+			tokens = append(tokens, ruleToken{kind: "string", value: b.String()})
+			operandExpected = false
+		case c == '/' && operandExpected:
+			var b strings.Builder
+			i++
+			for ; i < len(src) && src[i] != '/'; i++ {
+				if src[i] == '\\' && i+1 < len(src) {
+					b.WriteByte(src[i])
+					i++
+				}
+				b.WriteByte(src[i])
+			}
+			if i == len(src) {
+				return nil, fmt.Errorf("unterminated regular expression")
+			}
+			i++
+			pattern := b.String()
+			if i < len(src) && src[i] == 'i' {
+				pattern = "(?i)" + pattern
+				i++
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression: %w", err)
+			}
+			tokens = append(tokens, ruleToken{kind: "regex", value: re})
+			operandExpected = false
+		default:
+			op := ""
+			for _, candidate := range []string{"===", "!==", "==", "!=", "<=", ">=", "&&", "||", "!", "<", ">", "+", "-", "*", "/", "%", "?", ":", ".", "(", ")", "[", "]", ","} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+			i += len(op)
+			tokens = append(tokens, ruleToken{kind: "op", text: op})
+			operandExpected = op != ")" && op != "]"
+		}
+	}
+	return append(tokens, ruleToken{kind: "end"}), nil
+}
+
+// ruleParser is a precedence-climbing parser over the tokens of one expression.
+type ruleParser struct {
+	tokens []ruleToken
+	pos    int
+}
+
+func parseRuleExpr(src string) (ruleExpr, error) {
+	tokens, err := lexRule(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &ruleParser{tokens: tokens}
+	expr, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != "end" {
+		return nil, fmt.Errorf("unexpected %q", p.peek().text)
+	}
+	return expr, nil
+}
+
+func (p *ruleParser) peek() ruleToken { return p.tokens[p.pos] }
+
+func (p *ruleParser) accept(op string) bool {
+	if t := p.peek(); t.kind == "op" && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *ruleParser) expect(op string) error {
+	if !p.accept(op) {
+		return fmt.Errorf("expected %q", op)
+	}
+	return nil
+}
+
+func (p *ruleParser) ternary() (ruleExpr, error) {
+	cond, err := p.binary(0)
+	if err != nil || !p.accept("?") {
+		return cond, err
+	}
+	then, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	return ternaryExpr{cond, then, otherwise}, nil
+}
+
+// binaryLevels lists binary operators from the loosest binding to the tightest.
+var binaryLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"===", "!==", "==", "!="},
+	{"<=", ">=", "<", ">"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *ruleParser) binary(level int) (ruleExpr, error) {
+	if level == len(binaryLevels) {
+		return p.unary()
+	}
+	x, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		matched := false
+		for _, op := range binaryLevels[level] {
+			if t.kind == "op" && t.text == op {
+				matched = true
+			}
+		}
+		if !matched {
+			return x, nil
+		}
+		p.pos++
+		y, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = binaryExpr{t.text, x, y}
+	}
+}
+
+func (p *ruleParser) unary() (ruleExpr, error) {
+	for _, op := range []string{"!", "-"} {
+		if p.accept(op) {
+			x, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			return unaryExpr{op, x}, nil
+		}
+	}
+	return p.postfix()
+}
+
+func (p *ruleParser) postfix() (ruleExpr, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			t := p.peek()
+			if t.kind != "ident" {
+				return nil, fmt.Errorf("expected a name after \".\"")
+			}
+			p.pos++
+			if !p.accept("(") {
+				x = memberExpr{x, t.text}
+				continue
+			}
+			args, err := p.list(")")
+			if err != nil {
+				return nil, err
+			}
+			x = callExpr{x, t.text, args}
+		case p.accept("["):
+			key, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = indexExpr{x, key}
+		default:
+			return x, nil
+		}
+	}
+}
+
+func (p *ruleParser) list(closing string) ([]ruleExpr, error) {
+	var elems []ruleExpr
+	for !p.accept(closing) {
+		if len(elems) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		elem, err := p.ternary()
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+	}
+	return elems, nil
+}
+
+func (p *ruleParser) primary() (ruleExpr, error) {
+	t := p.peek()
+	switch t.kind {
+	case "number", "string", "regex":
+		p.pos++
+		return literalExpr{t.value}, nil
+	case "ident":
+		p.pos++
+		switch t.text {
+		case "true":
+			return literalExpr{true}, nil
+		case "false":
+			return literalExpr{false}, nil
+		case "null":
+			return literalExpr{nil}, nil
+		}
+		return identExpr{t.text}, nil
+	case "op":
+		switch {
+		case p.accept("("):
+			x, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		case p.accept("["):
+			elems, err := p.list("]")
+			return arrayExpr{elems}, err
+		}
+	case "end":
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+// ruleSnapshot is a RuleDataSnapshot: a location in a version of the tree.
+type ruleSnapshot struct {
+	root any
+	path []string
+}
+
+func (s ruleSnapshot) value() any { return getAt(s.root, s.path) }
+
+// ruleEnv holds the variables an expression can refer to.
+type ruleEnv map[string]any
+
+func evalRule(expr ruleExpr, env ruleEnv) (any, error) {
+	switch e := expr.(type) {
+	case literalExpr:
+		return e.value, nil
+	case identExpr:
+		value, ok := env[e.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown variable %s", e.name)
+		}
+		return value, nil
+	case arrayExpr:
+		values := make([]any, len(e.elems))
+		for i, elem := range e.elems {
+			value, err := evalRule(elem, env)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+	case memberExpr:
+		object, err := evalRule(e.object, env)
+		if err != nil {
+			return nil, err
+		}
+		return ruleProperty(object, e.name)
+	case indexExpr:
+		object, err := evalRule(e.object, env)
+		if err != nil {
+			return nil, err
+		}
+		key, err := evalRule(e.key, env)
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("index must be a string")
+		}
+		return ruleProperty(object, name)
+	case callExpr:
+		object, err := evalRule(e.object, env)
+		if err != nil {
+			return nil, err
+		}
+		args := make([]any, len(e.args))
+		for i, arg := range e.args {
+			if args[i], err = evalRule(arg, env); err != nil {
+				return nil, err
+			}
+		}
+		return ruleMethod(object, e.method, args)
+	case unaryExpr:
+		x, err := evalRule(e.x, env)
+		if err != nil {
+			return nil, err
+		}
+		if e.op == "!" {
+			b, ok := x.(bool)
+			if !ok {
+				return nil, fmt.Errorf("! needs a boolean")
+			}
+			return !b, nil
+		}
+		n, ok := x.(float64)
+		if !ok {
+			return nil, fmt.Errorf("- needs a number")
+		}
+		return -n, nil
+	case ternaryExpr:
+		cond, err := evalRule(e.cond, env)
+		if err != nil {
+			return nil, err
+		}
+		b, ok := cond.(bool)
+		if !ok {
+			return nil, fmt.Errorf("condition must be a boolean")
+		}
+		if b {
+			return evalRule(e.then, env)
+		}
+		return evalRule(e.otherwise, env)
+	case binaryExpr:
+		return evalBinary(e, env)
+	}
+	return nil, fmt.Errorf("unsupported expression %T", expr)
+}
+
+func evalBinary(e binaryExpr, env ruleEnv) (any, error) {
+	x, err := evalRule(e.x, env)
+	if err != nil {
+		return nil, err
+	}
+	if e.op == "&&" || e.op == "||" {
+		b, ok := x.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s needs booleans", e.op)
+		}
+		if b == (e.op == "||") {
+			return b, nil
+		}
+		y, err := evalRule(e.y, env)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := y.(bool); !ok {
+			return nil, fmt.Errorf("%s needs booleans", e.op)
+		}
+		return y, nil
+	}
+	y, err := evalRule(e.y, env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "==", "===":
+		return ruleEqual(x, y), nil
+	case "!=", "!==":
+		return !ruleEqual(x, y), nil
+	case "+":
+		xs, xString := x.(string)
+		ys, yString := y.(string)
+		if xString || yString {
+			if !xString {
+				xs = ruleString(x)
+			}
+			if !yString {
+				ys = ruleString(y)
+			}
+			return xs + ys, nil
+		}
+	case "<", "<=", ">", ">=":
+		if xs, ok := x.(string); ok {
+			ys, ok := y.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s needs two numbers or two strings", e.op)
+			}
+			c := strings.Compare(xs, ys)
+			return compareHolds(e.op, c), nil
+		}
+	}
+
+	xn, xok := x.(float64)
+	yn, yok := y.(float64)
+	if !xok || !yok {
+		return nil, fmt.Errorf("%s needs numbers", e.op)
+	}
+	switch e.op {
+	case "+":
+		return xn + yn, nil
+	case "-":
+		return xn - yn, nil
+	case "*":
+		return xn * yn, nil
+	case "/":
+		return xn / yn, nil
+	case "%":
+		return math.Mod(xn, yn), nil
+	}
+	c := 0
+	if xn < yn {
+		c = -1
+	} else if xn > yn {
+		c = 1
+	}
+	return compareHolds(e.op, c), nil
+}
+
+func compareHolds(op string, c int) bool {
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
+
+// ruleEqual compares primitives; objects and snapshots are never equal.
+func ruleEqual(x, y any) bool {
+	switch x.(type) {
+	case nil, bool, float64, string:
+		return x == y
+	}
+	return false
+}
+
+func ruleString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+func ruleProperty(object any, name string) (any, error) {
+	switch v := object.(type) {
+	case map[string]any:
+		return v[name], nil
+	case string:
+		if name == "length" {
+			return float64(len([]rune(v))), nil
+		}
+	case nil:
+		return nil, fmt.Errorf("cannot read %s of null", name)
+	}
+	return nil, fmt.Errorf("no property %s on %T", name, object)
+}
+
+func ruleMethod(object any, method string, args []any) (any, error) {
+	stringArg := func(i int) (string, error) {
+		if i >= len(args) {
+			return "", fmt.Errorf("%s needs %d argument(s)", method, i+1)
+		}
+		s, ok := args[i].(string)
+		if !ok {
+			return "", fmt.Errorf("%s needs a string argument", method)
+		}
+		return s, nil
+	}
+
+	switch v := object.(type) {
+	case ruleSnapshot:
+		value := v.value()
+		switch method {
+		case "val":
+			return value, nil
+		case "exists":
+			return value != nil, nil
+		case "child":
+			path, err := stringArg(0)
+			if err != nil {
+				return nil, err
+			}
+			return ruleSnapshot{v.root, append(append([]string(nil), v.path...), splitPath(path)...)}, nil
+		case "parent":
+			if len(v.path) == 0 {
+				return nil, fmt.Errorf("root has no parent")
+			}
+			return ruleSnapshot{v.root, v.path[:len(v.path)-1]}, nil
+		case "hasChild":
+			path, err := stringArg(0)
+			if err != nil {
+				return nil, err
+			}
+			return getAt(value, splitPath(path)) != nil, nil
+		case "hasChildren":
+			children, isObject := value.(map[string]any)
+			if len(args) == 0 {
+				return isObject && len(children) > 0, nil
+			}
+			names, ok := args[0].([]any)
+			if !ok {
+				return nil, fmt.Errorf("hasChildren needs an array of names")
+			}
+			for _, name := range names {
+				key, ok := name.(string)
+				if !ok {
+					return nil, fmt.Errorf("hasChildren needs an array of names")
+				}
+				if children[key] == nil {
+					return false, nil
+				}
+			}
+			return isObject, nil
+		case "isString":
+			_, ok := value.(string)
+			return ok, nil
+		case "isNumber":
+			_, ok := value.(float64)
+			return ok, nil
+		case "isBoolean":
+			_, ok := value.(bool)
+			return ok, nil
+		case "getPriority":
+			return nil, nil
+		}
+	case string:
+		switch method {
+		case "contains", "beginsWith", "endsWith":
+			s, err := stringArg(0)
+			if err != nil {
+				return nil, err
+			}
+			switch method {
+			case "contains":
+				return strings.Contains(v, s), nil
+			case "beginsWith":
+				return strings.HasPrefix(v, s), nil
+			}
+			return strings.HasSuffix(v, s), nil
+		case "replace":
+			old, err := stringArg(0)
+			if err != nil {
+				return nil, err
+			}
+			replacement, err := stringArg(1)
+			if err != nil {
+				return nil, err
+			}
+			return strings.ReplaceAll(v, old, replacement), nil
+		case "toLowerCase":
+			return strings.ToLower(v), nil
+		case "toUpperCase":
+			return strings.ToUpper(v), nil
+		case "matches":
+			if len(args) != 1 {
+				return nil, fmt.Errorf("matches needs a regular expression")
+			}
+			re, ok := args[0].(*regexp.Regexp)
+			if !ok {
+				return nil, fmt.Errorf("matches needs a regular expression")
+			}
+			return re.MatchString(v), nil
+		}
+	case nil:
+		return nil, fmt.Errorf("cannot call %s on null", method)
+	}
+	return nil, fmt.Errorf("no method %s on %T", method, object)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEvalRule(t *testing.T) {
+	tree := map[string]any{
+		"xoxo": map[string]any{
+			"orders": map[string]any{
+				"o1": map[string]any{"code": "ORD001", "status": "done", "total": float64(500000)},
+			},
+		},
+	}
+	env := ruleEnv{
+		"auth":    map[string]any{"uid": "u1", "token": map[string]any{"role": "sales"}},
+		"now":     float64(1000),
+		"root":    ruleSnapshot{root: tree},
+		"data":    ruleSnapshot{root: tree, path: []string{"xoxo", "orders", "o1"}},
+		"newData": ruleSnapshot{root: tree, path: []string{"xoxo", "orders", "o2"}},
+		"$id":     "o1",
+	}
+
+	tests := []struct {
+		src  string
+		want any
+	}{
+		{"true", true},
+		{"1 + 2 * 3", float64(7)},
+		{"(1 + 2) * 3", float64(9)},
+		{"10 % 4 - -1", float64(3)},
+		{"'a' + 1", "a1"},
+		{"auth != null && auth.uid == 'u1'", true},
+		{"auth.token.role === 'admin' || auth.token.role === 'sales'", true},
+		{"auth.token['role'] == 'sales'", true},
+		{"!(now > 500)", false},
+		{"now >= 1000 ? 'late' : 'early'", "late"},
+		{"data.exists()", true},
+		{"newData.exists()", false},
+		{"data.child('status').val() == 'done'", true},
+		{"data.child('total').isNumber() && data.child('total').val() > 0", true},
+		{"data.hasChildren(['code', 'status'])", true},
+		{"data.hasChildren(['code', 'missing'])", false},
+		{"data.hasChild('code')", true},
+		{"data.parent().child($id).exists()", true},
+		{"root.child('xoxo/orders/' + $id + '/code').val().beginsWith('ORD')", true},
+		{"data.child('code').val().length", float64(6)},
+		{"data.child('code').val().matches(/^ORD[0-9]+$/)", true},
+		{"'Abc'.toLowerCase() == 'abc'", true},
+		{"'abc'.toUpperCase().endsWith('BC')", true},
+		{"'a-b'.replace('-', '') == 'ab'", true},
+		{"false && auth.missing.role == 'x'", false},
+		{"true || auth.missing.role == 'x'", true},
+	}
+	for _, test := range tests {
+		expr, err := parseRuleExpr(test.src)
+		if err != nil {
+			t.Errorf("parseRuleExpr(%q): %v", test.src, err)
+			continue
+		}
+		got, err := evalRule(expr, env)
+		if err != nil {
+			t.Errorf("evalRule(%q): %v", test.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("evalRule(%q) = %#v, want %#v", test.src, got, test.want)
+		}
+	}
+}
+
+func TestEvalRuleErrors(t *testing.T) {
+	env := ruleEnv{"auth": nil, "now": float64(0)}
+	tests := []string{
+		"auth.uid == 'u1'",
+		"unknown == 1",
+		"!now",
+		"now ? 1 : 2",
+		"'a'.val()",
+	}
+	for _, src := range tests {
+		expr, err := parseRuleExpr(src)
+		if err != nil {
+			t.Errorf("parseRuleExpr(%q): %v", src, err)
+			continue
+		}
+		if got, err := evalRule(expr, env); err == nil {
+			t.Errorf("evalRule(%q) = %#v, want an error", src, got)
+		}
+	}
+}
+
+func TestParseRuleExprErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"1 +",
+		"(1",
+		"auth.",
+		"a ? b",
+		"'unterminated",
+		"1 2",
+	}
+	for _, src := range tests {
+		if _, err := parseRuleExpr(src); err == nil {
+			t.Errorf("parseRuleExpr(%q) succeeded, want an error", src)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRuleChecker(t *testing.T) {
+	rules, err := compileRules(map[string]any{
+		"a": map[string]any{
+			".read": "auth.uid == 'x'",
+			"b": map[string]any{
+				".read": "true",
+			},
+			"c": map[string]any{
+				".read": "auth.token.role == 'admin'",
+			},
+		},
+		"items": map[string]any{
+			".read": "auth != null",
+			"$id": map[string]any{
+				".write":    "auth != null && (!data.exists() || data.child('owner').val() == auth.uid)",
+				".validate": "newData.hasChildren(['owner', 'qty'])",
+				"qty": map[string]any{
+					".validate": "newData.isNumber() && newData.val() >= 0",
+				},
+			},
+		},
+	}, "")
+	if err != nil {
+		t.Fatalf("compileRules: %v", err)
+	}
+	root := map[string]any{
+		"a": map[string]any{"b": "open", "c": "closed"},
+		"items": map[string]any{
+			"i1": map[string]any{"owner": "u1", "qty": float64(2)},
+		},
+	}
+	signedIn := map[string]any{"uid": "u1", "token": map[string]any{"role": "sales"}}
+
+	tests := []struct {
+		name       string
+		auth       map[string]any
+		op         string
+		path       string
+		data       map[string]any
+		allow      bool
+		wantReason string
+	}{
+		{name: "errored rule above a grant counts as false", op: "read", path: "a/b", allow: true},
+		{name: "errored rule without a grant below denies", op: "read", path: "a/c", allow: false, wantReason: ".read at /a failed: cannot read uid of null"},
+		{name: "errored rule at the path denies", op: "read", path: "a", allow: false, wantReason: "failed"},
+		{name: "signed in reads above the grant", auth: signedIn, op: "read", path: "a/b", allow: true},
+		{name: "false rule denies", auth: signedIn, op: "read", path: "a/c", allow: false},
+		{name: "read a collection", auth: signedIn, op: "read", path: "items", allow: true},
+		{name: "signed out cannot read a collection", op: "read", path: "items", allow: false},
+		{
+			name: "owner updates a record", auth: signedIn, op: "update", path: "items/i1",
+			data: map[string]any{"qty": float64(3)}, allow: true,
+		},
+		{
+			name: "other user cannot update a record", auth: map[string]any{"uid": "u2"}, op: "update", path: "items/i1",
+			data: map[string]any{"qty": float64(3)}, allow: false,
+		},
+		{
+			name: "new record is validated", auth: signedIn, op: "set", path: "items/i2",
+			data: map[string]any{"owner": "u1"}, allow: false, wantReason: ".validate at /items/i2",
+		},
+		{
+			name: "nested field is validated", auth: signedIn, op: "update", path: "items/i1",
+			data: map[string]any{"qty": float64(-1)}, allow: false, wantReason: ".validate at /items/i1/qty",
+		},
+		{
+			name: "valid new record", auth: map[string]any{"uid": "u2"}, op: "set", path: "items/i2",
+			data: map[string]any{"owner": "u2", "qty": float64(0)}, allow: true,
+		},
+		{
+			name: "multi-path update needs every path granted", auth: signedIn, op: "update", path: "",
+			data: map[string]any{"items/i1/qty": float64(1), "a/b": "x"}, allow: false, wantReason: "no .write rule grants /a/b",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checker := newRuleChecker(rules, root, test.auth)
+			path := splitPath(test.path)
+			var decision Decision
+			switch test.op {
+			case "read":
+				decision = checker.CheckRead(path)
+			case "set":
+				decision = checker.CheckUpdate(path[:len(path)-1], map[string]any{path[len(path)-1]: test.data})
+			case "update":
+				decision = checker.CheckUpdate(path, test.data)
+			}
+			if decision.Allowed != test.allow {
+				t.Fatalf("allowed = %v (%s), want %v", decision.Allowed, decision.Reason, test.allow)
+			}
+			if !strings.Contains(decision.Reason, test.wantReason) {
+				t.Errorf("reason = %q, want it to mention %q", decision.Reason, test.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// The emulators' default addresses and a demo project ID, which the
+// emulators serve without credentials.
+const (
+	defaultDatabaseEmulatorHost = "127.0.0.1:9000"
+	defaultAuthEmulatorHost     = "127.0.0.1:9099"
+	defaultProjectID            = "demo-xoxo"
+)
+
+// DatabaseOptions says which Realtime Database, and which tree in it, a
+// command works on.
+type DatabaseOptions struct {
+	ProjectID   string
+	DatabaseURL string
+	// Namespace defaults to the project's default database instance.
+	Namespace string
+	// Auth is a database secret or ID token sent as the auth parameter.
+	// Without one, requests to the emulator are made as its owner, which
+	// bypasses security rules.
+	Auth    string
+	Root    string
+	Retries int
+	// AllowRemote lets commands reach a database other than a local
+	// emulator. Seed and purge delete what is there.
+	AllowRemote bool
+}
+
+func (o *DatabaseOptions) register(flags *flag.FlagSet) {
+	emulatorHost := os.Getenv("FIREBASE_DATABASE_EMULATOR_HOST")
+	if emulatorHost == "" {
+		emulatorHost = defaultDatabaseEmulatorHost
+	}
+	flags.StringVar(&o.ProjectID, "project", defaultProjectID, "Firebase project ID")
+	flags.StringVar(&o.DatabaseURL, "database-url", "http://"+emulatorHost, "Realtime Database URL; defaults to the emulator ($FIREBASE_DATABASE_EMULATOR_HOST)")
+	flags.StringVar(&o.Namespace, "ns", "", "database namespace, sent to http:// (emulator) URLs only (default <project>-default-rtdb)")
+	flags.StringVar(&o.Auth, "auth", os.Getenv("FIREBASE_DATABASE_AUTH"), "database secret or ID token ($FIREBASE_DATABASE_AUTH)")
+	flags.StringVar(&o.Root, "root", "xoxo", "path of the generated tree")
+	flags.IntVar(&o.Retries, "retries", 3, "retries for a request that fails with a network error, 429 or 5xx")
+	flags.BoolVar(&o.AllowRemote, "allow-remote", false, "allow a -database-url other than the local emulator; seed and purge delete what is there")
+}
+
+// isEmulatorHost reports whether a database URL's host is a local emulator:
+// localhost, a loopback address or $FIREBASE_DATABASE_EMULATOR_HOST.
+func isEmulatorHost(host string) bool {
+	if emulatorHost := os.Getenv("FIREBASE_DATABASE_EMULATOR_HOST"); emulatorHost != "" && strings.EqualFold(host, emulatorHost) {
+		return true
+	}
+	hostname := host
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		hostname = name
+	}
+	if strings.EqualFold(hostname, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(hostname)
+	return ip != nil && ip.IsLoopback()
+}
+
+// client connects to the database, refusing any but a local emulator unless
+// AllowRemote is set, so that a stray -database-url cannot wipe production.
+func (o DatabaseOptions) client() (*RTDBClient, error) {
+	if base, err := url.Parse(o.DatabaseURL); err == nil && base.Host != "" && !isEmulatorHost(base.Host) && !o.AllowRemote {
+		return nil, fmt.Errorf("%s is not a local emulator; pass -allow-remote to use it anyway", o.DatabaseURL)
+	}
+	namespace := o.Namespace
+	if namespace == "" {
+		namespace = o.ProjectID + "-default-rtdb"
+	}
+	return newRTDBClient(o.DatabaseURL, namespace, o.Auth, o.Retries)
+}
+
+// SeedOptions configures where and how the seed command writes the tree.
+type SeedOptions struct {
+	DatabaseOptions
+	// ChunkSize is the largest request body in bytes; bigger collections are
+	// written in several requests.
+	ChunkSize int
+
+	Accounts AccountOptions
+	Images   ImageOptions
+}
+
+func (o *SeedOptions) register(flags *flag.FlagSet) {
+	o.DatabaseOptions.register(flags)
+	flags.IntVar(&o.ChunkSize, "chunk-size", 1<<20, "largest request body in bytes")
+	o.Accounts.register(flags)
+	o.Images.register(flags)
+}
+
+// RTDBClient talks to a Realtime Database over its REST API.
+type RTDBClient struct {
+	baseURL   *url.URL
+	namespace string
+	auth      string
+	retries   int
+	http      *http.Client
+}
+
+func newRTDBClient(databaseURL, namespace, auth string, retries int) (*RTDBClient, error) {
+	base, err := url.Parse(strings.TrimSuffix(databaseURL, "/"))
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid database URL %q", databaseURL)
+	}
+	if base.Scheme != "http" {
+		namespace = ""
+	}
+	return &RTDBClient{
+		baseURL:   base,
+		namespace: namespace,
+		auth:      auth,
+		retries:   retries,
+		http:      &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+// endpoint returns the REST URL of a slash-separated database path.
+func (c *RTDBClient) endpoint(path string, query url.Values) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	u := *c.baseURL
+	u.Path += "/" + strings.Join(segments, "/") + ".json"
+	if query == nil {
+		query = url.Values{}
+	}
+	if c.namespace != "" {
+		query.Set("ns", c.namespace)
+	}
+	if c.auth != "" {
+		query.Set("auth", c.auth)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// Do sends a request and returns the response body.
+func (c *RTDBClient) Do(method, path string, query url.Values, body []byte) ([]byte, error) {
+	respBody, err := doWithRetries(c.http, c.retries, func() (*http.Request, error) {
+		req, err := http.NewRequest(method, c.endpoint(path, query), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if c.auth == "" && c.baseURL.Scheme == "http" {
+			req.Header.Set("Authorization", "Bearer owner")
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s /%s: %w", method, path, err)
+	}
+	return respBody, nil
+}
+
+// doWithRetries sends the request built by newRequest and returns the
+// response body, retrying network errors, 429 and 5xx responses with
+// exponential backoff. A refused connection is not retried: the emulator is
+// not running.
+func doWithRetries(client *http.Client, retries int, newRequest func() (*http.Request, error)) ([]byte, error) {
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(500 * time.Millisecond << (attempt - 1))
+		}
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			if errors.Is(err, syscall.ECONNREFUSED) {
+				return nil, fmt.Errorf("%w (is the emulator running? start it with: firebase emulators:start)", err)
+			}
+			lastErr = err
+			continue
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("read response: %w", err)
+			continue
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return respBody, nil
+		}
+		lastErr = fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return nil, lastErr
+		}
+	}
+	return nil, fmt.Errorf("giving up after %d attempts: %w", retries+1, lastErr)
+}
+
+// chunkWriter writes a JSON tree in requests no larger than limit bytes.
+type chunkWriter struct {
+	client   *RTDBClient
+	limit    int
+	requests int
+	bytes    int
+}
+
+func (w *chunkWriter) send(method, path string, body []byte) error {
+	if _, err := w.client.Do(method, path, nil, body); err != nil {
+		return err
+	}
+	w.requests++
+	w.bytes += len(body)
+	return nil
+}
+
+// write stores value at path. A value over the limit is split into PATCH
+// requests of whole children; a single child over the limit is split in turn.
+// The path must be empty beforehand, as PATCH keeps children it is not sent.
+func (w *chunkWriter) write(path string, value json.RawMessage) error {
+	if len(value) <= w.limit {
+		return w.send(http.MethodPut, path, value)
+	}
+
+	var children map[string]json.RawMessage
+	if err := json.Unmarshal(value, &children); err != nil {
+		return fmt.Errorf("/%s is %d bytes, over the %d byte chunk size, and is not an object that can be split", path, len(value), w.limit)
+	}
+	keys := make([]string, 0, len(children))
+	for key := range children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	batch := make(map[string]json.RawMessage)
+	batchSize := 2
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		body, err := json.Marshal(batch)
+		if err != nil {
+			return err
+		}
+		batch = make(map[string]json.RawMessage)
+		batchSize = 2
+		return w.send(http.MethodPatch, path, body)
+	}
+
+	for _, key := range keys {
+		child := children[key]
+		entrySize := len(key) + len(child) + 4
+		if entrySize > w.limit-2 {
+			if err := w.write(path+"/"+key, child); err != nil {
+				return err
+			}
+			continue
+		}
+		if batchSize+entrySize > w.limit {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		batch[key] = child
+		batchSize += entrySize
+	}
+	return flush()
+}
+
+// seedDatabase replaces the tree at options.Root with the generated data, one
+// collection at a time, and reports progress as it goes. Login accounts are
+// created first so members carry their Auth UIDs, and images are uploaded first
+// so the data carries their download URLs.
+func seedDatabase(data MockData, options SeedOptions) error {
+	if options.ChunkSize < 1024 {
+		return fmt.Errorf("-chunk-size must be at least 1024 bytes, got %d", options.ChunkSize)
+	}
+	client, err := options.client()
+	if err != nil {
+		return err
+	}
+
+	if options.Accounts.Enabled {
+		created, err := createLoginAccounts(data.Xoxo.Members, options.ProjectID, options.Accounts, options.Retries)
+		if err != nil {
+			return fmt.Errorf("create login accounts: %w", err)
+		}
+		fmt.Printf("Created %d Auth emulator accounts at %s\n", created, options.Accounts.EmulatorHost)
+		for _, id := range []string{"ADMIN_FIXED_001", "SALES_FIXED_001", "WORKER_FIXED_001"} {
+			if member, ok := data.Xoxo.Members[id]; ok {
+				fmt.Printf("  %-8s %s / %s\n", member.Role, member.Email, options.Accounts.passwordFor(member.Role))
+			}
+		}
+	}
+
+	if options.Images.Enabled {
+		urls, err := imageStore.Upload(options.Retries)
+		if err != nil {
+			return fmt.Errorf("upload images: %w", err)
+		}
+		rewriteImageURLs(&data, urls)
+		fmt.Printf("Uploaded %d placeholder images to gs://%s at %s\n", len(urls), imageStore.bucket, imageStore.host)
+	}
+
+	raw, err := json.Marshal(data.Xoxo)
+	if err != nil {
+		return fmt.Errorf("marshal data: %w", err)
+	}
+	var collections map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &collections); err != nil {
+		return fmt.Errorf("split data into collections: %w", err)
+	}
+	names := make([]string, 0, len(collections))
+	for name := range collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	root := strings.Trim(options.Root, "/")
+	fmt.Printf("Seeding %s/%s (%d collections, %d KB)\n", client.baseURL, root, len(names), len(raw)/1024)
+	if _, err := client.Do(http.MethodDelete, root, nil, nil); err != nil {
+		return fmt.Errorf("clear /%s: %w", root, err)
+	}
+
+	for i, name := range names {
+		writer := &chunkWriter{client: client, limit: options.ChunkSize}
+		if err := writer.write(root+"/"+name, collections[name]); err != nil {
+			return fmt.Errorf("write %s: %w", name, err)
+		}
+		fmt.Printf("  [%2d/%d] %-26s %6d KB in %d request(s)\n", i+1, len(names), name, writer.bytes/1024, writer.requests)
+	}
+	return nil
+}
@@ -0,0 +1,558 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keepAliveInterval is how often an idle event stream sends a keep-alive.
+const keepAliveInterval = 30 * time.Second
+
+// listenerBuffer is how many events a stream may fall behind by before it is
+// cancelled.
+const listenerBuffer = 256
+
+// pushChars are the characters of push IDs, in ascending order so IDs sort by
+// creation time.
+const pushChars = "-0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ_abcdefghijklmnopqrstuvwxyz"
+
+// Database is an in-memory tree served with the Realtime Database REST API
+// and wire protocol. Values are stored as the database stores them: objects
+// without empty children, arrays as objects keyed by index, numbers as
+// float64.
+type Database struct {
+	mu        sync.RWMutex
+	root      any
+	listeners map[*listener]bool
+
+	lastPushTime int64
+	lastPushRand [12]int
+}
+
+// listener is an open subscription to a path, optionally with a query: an
+// event stream, or a listen a client holds over a WebSocket connection.
+type listener struct {
+	path  []string
+	query *Query
+	// events queues an event stream's events.
+	events chan []byte
+	// session holds a WebSocket listen; tag is the client's number for a
+	// listen with a query, zero without one.
+	session *wireSession
+	tag     float64
+}
+
+func newDatabase(root any) (*Database, error) {
+	normalized, err := normalizeValue(root, time.Now().UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	return &Database{root: normalized, listeners: make(map[*listener]bool)}, nil
+}
+
+// splitPath turns a slash-separated path into its segments.
+func splitPath(path string) []string {
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// parsePath splits a request path and checks its segments are valid keys.
+func parsePath(path string) ([]string, error) {
+	segments := splitPath(path)
+	for _, segment := range segments {
+		if err := validateKey(segment); err != nil {
+			return nil, err
+		}
+	}
+	return segments, nil
+}
+
+func hasPathPrefix(path, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func getAt(node any, segments []string) any {
+	for _, segment := range segments {
+		children, ok := node.(map[string]any)
+		if !ok {
+			return nil
+		}
+		node = children[segment]
+	}
+	return node
+}
+
+// setAt stores value, or deletes with nil, at segments under node and returns
+// the new node. Objects left empty are removed.
+func setAt(node any, segments []string, value any) any {
+	if len(segments) == 0 {
+		return value
+	}
+	children, ok := node.(map[string]any)
+	if !ok {
+		if value == nil {
+			return node
+		}
+		children = make(map[string]any)
+	}
+	child := setAt(children[segments[0]], segments[1:], value)
+	if child == nil {
+		delete(children, segments[0])
+	} else {
+		children[segments[0]] = child
+	}
+	if len(children) == 0 {
+		return nil
+	}
+	return children
+}
+
+// normalizeValue converts a written JSON value into the stored form, filling
+// in {".sv": "timestamp"} server values and rejecting keys the database does
+// not allow.
+func normalizeValue(value any, now int64) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		if sv, ok := v[".sv"]; ok && len(v) == 1 {
+			if sv != "timestamp" {
+				return nil, fmt.Errorf("unsupported server value %v", sv)
+			}
+			return float64(now), nil
+		}
+		children := make(map[string]any, len(v))
+		for key, child := range v {
+			if key == ".priority" {
+				continue
+			}
+			if err := validateKey(key); err != nil {
+				return nil, err
+			}
+			normalized, err := normalizeValue(child, now)
+			if err != nil {
+				return nil, err
+			}
+			if normalized != nil {
+				children[key] = normalized
+			}
+		}
+		if len(children) == 0 {
+			return nil, nil
+		}
+		return children, nil
+	case []any:
+		children := make(map[string]any, len(v))
+		for i, child := range v {
+			normalized, err := normalizeValue(child, now)
+			if err != nil {
+				return nil, err
+			}
+			if normalized != nil {
+				children[strconv.Itoa(i)] = normalized
+			}
+		}
+		if len(children) == 0 {
+			return nil, nil
+		}
+		return children, nil
+	}
+	return value, nil
+}
+
+func validateKey(key string) error {
+	if key == "" || len(key) > 768 || strings.ContainsAny(key, ".$#[]/") {
+		return fmt.Errorf("invalid key %q: keys must be non-empty and can't contain \".\", \"#\", \"$\", \"/\", \"[\" or \"]\"", key)
+	}
+	for _, r := range key {
+		if r < 0x20 || r == 0x7f {
+			return fmt.Errorf("invalid key %q: keys can't contain control characters", key)
+		}
+	}
+	return nil
+}
+
+// exportValue converts a stored value into what a read returns: objects whose
+// keys are mostly array indices come back as arrays.
+func exportValue(value any) any {
+	children, ok := value.(map[string]any)
+	if !ok {
+		return value
+	}
+	maxIndex, isArray := -1, true
+	for key := range children {
+		index, ok := intKey(key)
+		if !ok || index < 0 {
+			isArray = false
+			break
+		}
+		maxIndex = max(maxIndex, int(index))
+	}
+	if isArray && 2*len(children) > maxIndex+1 {
+		array := make([]any, maxIndex+1)
+		for key, child := range children {
+			index, _ := intKey(key)
+			array[index] = exportValue(child)
+		}
+		return array
+	}
+	exported := make(map[string]any, len(children))
+	for key, child := range children {
+		exported[key] = exportValue(child)
+	}
+	return exported
+}
+
+// pushID returns a key for POST that sorts after every key pushed before it,
+// as the client SDKs generate them. Callers hold d.mu.
+func (d *Database) pushID(now int64) string {
+	var id [20]byte
+	if now == d.lastPushTime {
+		for i := len(d.lastPushRand) - 1; i >= 0; i-- {
+			d.lastPushRand[i]++
+			if d.lastPushRand[i] < len(pushChars) {
+				break
+			}
+			d.lastPushRand[i] = 0
+		}
+	} else {
+		for i := range d.lastPushRand {
+			d.lastPushRand[i] = rand.Intn(len(pushChars))
+		}
+	}
+	d.lastPushTime = now
+	for i := 7; i >= 0; i-- {
+		id[i] = pushChars[now%int64(len(pushChars))]
+		now /= int64(len(pushChars))
+	}
+	for i, r := range d.lastPushRand {
+		id[8+i] = pushChars[r]
+	}
+	return string(id[:])
+}
+
+func (d *Database) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/.ws" {
+		d.serveWire(w, r)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, POST, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-HTTP-Method-Override")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if !strings.HasSuffix(r.URL.Path, ".json") {
+		writeError(w, http.StatusNotFound, "paths must end in .json")
+		return
+	}
+	path, err := parsePath(strings.TrimSuffix(r.URL.Path, ".json"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	method := r.Method
+	if override := r.Header.Get("X-HTTP-Method-Override"); override != "" && method == http.MethodPost {
+		method = strings.ToUpper(override)
+	}
+	params := r.URL.Query()
+
+	if method == http.MethodGet {
+		if params.Get("shallow") == "true" && (params.Has("orderBy") || params.Has("limitToFirst") || params.Has("limitToLast")) {
+			writeError(w, http.StatusBadRequest, "shallow cannot be mixed with any other query parameters")
+			return
+		}
+		query, err := parseQuery(params)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			d.stream(w, r, path, query)
+			return
+		}
+		d.mu.RLock()
+		value := d.read(path, query, params.Get("shallow") == "true")
+		d.mu.RUnlock()
+		writeValue(w, r, value)
+		return
+	}
+
+	var body any
+	if method != http.MethodDelete {
+		decoder := json.NewDecoder(r.Body)
+		if err := decoder.Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid data; couldn't parse JSON object, array, or value")
+			return
+		}
+	}
+	now := time.Now().UnixMilli()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch method {
+	case http.MethodPut, http.MethodDelete:
+		value, err := d.put(path, body, now)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeValue(w, r, exportValue(value))
+	case http.MethodPost:
+		name := d.pushID(now)
+		if _, err := d.put(append(append([]string(nil), path...), name), body, now); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeValue(w, r, map[string]string{"name": name})
+	case http.MethodPatch:
+		updates, ok := body.(map[string]any)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "invalid data; a PATCH must be a JSON object")
+			return
+		}
+		if err := d.merge(path, updates, now); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeValue(w, r, body)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// put stores value at path, or deletes what is there when it is null, and
+// notifies the listeners. It returns the value as stored. Callers hold d.mu.
+func (d *Database) put(path []string, value any, now int64) (any, error) {
+	normalized, err := normalizeValue(value, now)
+	if err != nil {
+		return nil, err
+	}
+	d.root = setAt(d.root, path, normalized)
+	d.notify(path, [][]string{path}, false)
+	return normalized, nil
+}
+
+// merge writes updates, keyed by paths relative to base, and notifies the
+// listeners. Every update is validated before any is applied, so a bad one
+// changes nothing. Callers hold d.mu.
+func (d *Database) merge(base []string, updates map[string]any, now int64) error {
+	paths := make([][]string, 0, len(updates))
+	values := make([]any, 0, len(updates))
+	for key, update := range updates {
+		relative, err := parsePath(key)
+		if err != nil {
+			return err
+		}
+		value, err := normalizeValue(update, now)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, append(append([]string(nil), base...), relative...))
+		values = append(values, value)
+	}
+	for i, path := range paths {
+		d.root = setAt(d.root, path, values[i])
+	}
+	d.notify(base, paths, true)
+	return nil
+}
+
+// read returns what a GET of path returns. Callers hold d.mu.
+func (d *Database) read(path []string, query *Query, shallow bool) any {
+	value := getAt(d.root, path)
+	if query != nil {
+		value = query.Apply(value)
+	}
+	if children, ok := value.(map[string]any); ok && shallow {
+		keys := make(map[string]any, len(children))
+		for key, child := range children {
+			if _, isObject := child.(map[string]any); isObject {
+				keys[key] = true
+			} else {
+				keys[key] = child
+			}
+		}
+		return keys
+	}
+	return exportValue(value)
+}
+
+// notify sends listeners the events for a write. A PUT, POST or DELETE wrote
+// the one path in paths; a PATCH at base wrote all of them. Callers hold d.mu.
+func (d *Database) notify(base []string, paths [][]string, patch bool) {
+	for l := range d.listeners {
+		related := false
+		for _, path := range paths {
+			if hasPathPrefix(path, l.path) || hasPathPrefix(l.path, path) {
+				related = true
+				break
+			}
+		}
+		if !related {
+			continue
+		}
+
+		switch {
+		case l.query != nil:
+			// A query's result can change as a whole: send it again.
+			d.send(l, "put", nil, d.read(l.path, l.query, false))
+		case patch && hasPathPrefix(base, l.path):
+			data := make(map[string]any, len(paths))
+			for _, path := range paths {
+				data[strings.Join(path[len(base):], "/")] = exportValue(getAt(d.root, path))
+			}
+			d.send(l, "patch", base[len(l.path):], data)
+		default:
+			for _, path := range paths {
+				if hasPathPrefix(l.path, path) {
+					d.send(l, "put", nil, d.read(l.path, nil, false))
+					break
+				}
+				if hasPathPrefix(path, l.path) {
+					d.send(l, "put", path[len(l.path):], exportValue(getAt(d.root, path)))
+				}
+			}
+		}
+	}
+}
+
+// send queues an event for a listener, cancelling it when it has fallen too
+// far behind. Callers hold d.mu.
+func (d *Database) send(l *listener, event string, path []string, data any) {
+	if !d.listeners[l] {
+		return
+	}
+	if l.session != nil {
+		l.session.push(l, event, path, data)
+		return
+	}
+	payload, _ := json.Marshal(map[string]any{"path": "/" + strings.Join(path, "/"), "data": data})
+	select {
+	case l.events <- []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", event, payload)):
+	default:
+		delete(d.listeners, l)
+		close(l.events)
+	}
+}
+
+// stream serves a GET with Accept: text/event-stream: the current value as a
+// put event, then a put or patch event for every write that touches it.
+func (d *Database) stream(w http.ResponseWriter, r *http.Request, path []string, query *Query) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	l := &listener{path: path, query: query, events: make(chan []byte, listenerBuffer)}
+	d.mu.Lock()
+	d.listeners[l] = true
+	d.send(l, "put", nil, d.read(path, query, false))
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		if d.listeners[l] {
+			delete(d.listeners, l)
+			close(l.events)
+		}
+		d.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-l.events:
+			if !ok {
+				fmt.Fprint(w, "event: cancel\ndata: null\n\n")
+				flusher.Flush()
+				return
+			}
+			w.Write(event)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, "event: keep-alive\ndata: null\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func writeValue(w http.ResponseWriter, r *http.Request, value any) {
+	switch r.URL.Query().Get("print") {
+	case "silent":
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case "pretty":
+		body, _ := json.MarshalIndent(value, "", "  ")
+		writeJSON(w, http.StatusOK, body)
+		return
+	}
+	body, _ := json.Marshal(value)
+	writeJSON(w, http.StatusOK, body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	body, _ := json.Marshal(map[string]string{"error": message})
+	writeJSON(w, status, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// serveCommand runs `serve [flags] [mock-data.json]`.
+func serveCommand(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", defaultDatabaseEmulatorHost, "address to listen on")
+	flags.Parse(args)
+
+	dataFile := "./mock-data.json"
+	if flags.NArg() > 0 {
+		dataFile = flags.Arg(0)
+	}
+	raw, err := os.ReadFile(dataFile)
+	if err != nil {
+		return err
+	}
+	var root any
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return fmt.Errorf("parse %s: %w", dataFile, err)
+	}
+	db, err := newDatabase(root)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", dataFile, err)
+	}
+
+	fmt.Printf("Serving %s (%d KB) at http://%s\n", dataFile, len(raw)/1024, *addr)
+	return http.ListenAndServe(*addr, db)
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// seedTree is the database each server test starts from.
+func seedTree() map[string]any {
+	return map[string]any{
+		"orders": map[string]any{
+			"o1": map[string]any{"status": "pending", "total": float64(100)},
+			"o2": map[string]any{"status": "done", "total": float64(200)},
+		},
+		"settings": map[string]any{"currency": "VND"},
+	}
+}
+
+// decodeJSON parses a test's expected JSON.
+func decodeJSON(t *testing.T, raw string) any {
+	t.Helper()
+	var value any
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		t.Fatalf("parse %s: %v", raw, err)
+	}
+	return value
+}
+
+func TestServerWrites(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		header     string
+		body       string
+		wantStatus int
+		wantBody   string
+		// check is read after the request and should return wantTree.
+		check    string
+		wantTree string
+	}{
+		{
+			name: "put replaces", method: "PUT", path: "/orders/o1.json", body: `{"status":"done"}`,
+			wantStatus: 200, wantBody: `{"status":"done"}`,
+			check: "/orders/o1.json", wantTree: `{"status":"done"}`,
+		},
+		{
+			name: "put null deletes and prunes empty parents", method: "PUT", path: "/settings/currency.json", body: `null`,
+			wantStatus: 200, wantBody: `null`,
+			check: "/.json", wantTree: `{"orders":{"o1":{"status":"pending","total":100},"o2":{"status":"done","total":200}}}`,
+		},
+		{
+			name: "delete", method: "DELETE", path: "/orders/o2.json",
+			wantStatus: 200, wantBody: `null`,
+			check: "/orders.json", wantTree: `{"o1":{"status":"pending","total":100}}`,
+		},
+		{
+			name: "put reads arrays back as arrays", method: "PUT", path: "/tags.json", body: `["a",null,"c"]`,
+			wantStatus: 200, wantBody: `["a",null,"c"]`,
+			check: "/tags/2.json", wantTree: `"c"`,
+		},
+		{
+			name: "put with an invalid key changes nothing", method: "PUT", path: "/orders/o1.json", body: `{"a.b":1}`,
+			wantStatus: 400,
+			check:      "/orders/o1.json", wantTree: `{"status":"pending","total":100}`,
+		},
+		{
+			name: "patch writes every path", method: "PATCH", path: "/orders.json",
+			body:       `{"o1/status":"done","o2":null,"o3":{"total":50}}`,
+			wantStatus: 200, wantBody: `{"o1/status":"done","o2":null,"o3":{"total":50}}`,
+			check: "/orders.json", wantTree: `{"o1":{"status":"done","total":100},"o3":{"total":50}}`,
+		},
+		{
+			name: "patch with an invalid key changes nothing", method: "PATCH", path: "/orders.json",
+			body:       `{"o1/status":"done","o1/total":1,"o2/status":"new","o2/total":2,"o3":{"total":3},"o2/bad#key":1}`,
+			wantStatus: 400,
+			check:      "/orders.json", wantTree: `{"o1":{"status":"pending","total":100},"o2":{"status":"done","total":200}}`,
+		},
+		{
+			name: "patch with an invalid value changes nothing", method: "PATCH", path: "/orders.json",
+			body:       `{"o1/status":"done","o1/total":1,"o2/status":"new","o2/total":2,"o3":{"total":3},"o4":{".sv":"increment"}}`,
+			wantStatus: 400,
+			check:      "/orders.json", wantTree: `{"o1":{"status":"pending","total":100},"o2":{"status":"done","total":200}}`,
+		},
+		{
+			name: "patch must be an object", method: "PATCH", path: "/orders.json", body: `[1]`,
+			wantStatus: 400,
+			check:      "/orders/o1/status.json", wantTree: `"pending"`,
+		},
+		{
+			name: "method override", method: "POST", path: "/orders/o1.json", header: "PATCH", body: `{"status":"done"}`,
+			wantStatus: 200,
+			check:      "/orders/o1.json", wantTree: `{"status":"done","total":100}`,
+		},
+		{
+			name: "invalid path", method: "PUT", path: "/orders/o$1.json", body: `1`,
+			wantStatus: 400,
+		},
+		{
+			name: "unparseable body", method: "PUT", path: "/orders/o1.json", body: `{`,
+			wantStatus: 400,
+			check:      "/orders/o1/status.json", wantTree: `"pending"`,
+		},
+		{
+			name: "path without .json", method: "GET", path: "/orders",
+			wantStatus: 404,
+		},
+		{
+			name: "query", method: "GET", path: `/orders.json?orderBy="total"&limitToLast=1`,
+			wantStatus: 200, wantBody: `{"o2":{"status":"done","total":200}}`,
+		},
+		{
+			name: "shallow", method: "GET", path: `/.json?shallow=true`,
+			wantStatus: 200, wantBody: `{"orders":true,"settings":true}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := newDatabase(seedTree())
+			if err != nil {
+				t.Fatalf("newDatabase: %v", err)
+			}
+			server := httptest.NewServer(db)
+			defer server.Close()
+
+			request, _ := http.NewRequest(test.method, server.URL+test.path, strings.NewReader(test.body))
+			if test.header != "" {
+				request.Header.Set("X-HTTP-Method-Override", test.header)
+			}
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatalf("%s %s: %v", test.method, test.path, err)
+			}
+			body, _ := io.ReadAll(response.Body)
+			response.Body.Close()
+			if response.StatusCode != test.wantStatus {
+				t.Fatalf("status = %d (%s), want %d", response.StatusCode, body, test.wantStatus)
+			}
+			if test.wantBody != "" && !reflect.DeepEqual(decodeJSON(t, string(body)), decodeJSON(t, test.wantBody)) {
+				t.Errorf("body = %s, want %s", body, test.wantBody)
+			}
+			if test.check == "" {
+				return
+			}
+			response, err = http.Get(server.URL + test.check)
+			if err != nil {
+				t.Fatalf("GET %s: %v", test.check, err)
+			}
+			body, _ = io.ReadAll(response.Body)
+			response.Body.Close()
+			if !reflect.DeepEqual(decodeJSON(t, string(body)), decodeJSON(t, test.wantTree)) {
+				t.Errorf("%s = %s, want %s", test.check, body, test.wantTree)
+			}
+		})
+	}
+}
+
+func TestServerPost(t *testing.T) {
+	db, err := newDatabase(seedTree())
+	if err != nil {
+		t.Fatalf("newDatabase: %v", err)
+	}
+	server := httptest.NewServer(db)
+	defer server.Close()
+
+	var names []string
+	for i := 0; i < 3; i++ {
+		response, err := http.Post(server.URL+"/logs.json", "application/json", strings.NewReader(`{"at":{".sv":"timestamp"}}`))
+		if err != nil {
+			t.Fatalf("POST: %v", err)
+		}
+		var result struct{ Name string }
+		json.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+		names = append(names, result.Name)
+	}
+	for i := 1; i < len(names); i++ {
+		if len(names[i]) != 20 || names[i] <= names[i-1] {
+			t.Fatalf("push IDs %v do not sort in the order they were made", names)
+		}
+	}
+	logs, _ := getAt(db.root, []string{"logs"}).(map[string]any)
+	for _, name := range names {
+		at, _ := getAt(logs, []string{name, "at"}).(float64)
+		if at < float64(time.Now().Add(-time.Minute).UnixMilli()) {
+			t.Errorf("logs/%s/at = %v, want the server time", name, at)
+		}
+	}
+}
+
+func TestServerStream(t *testing.T) {
+	type event struct{ name, data string }
+	tests := []struct {
+		name   string
+		stream string
+		writes []struct{ method, path, body string }
+		want   []event
+	}{
+		{
+			name:   "put below the listen",
+			stream: "/orders.json",
+			writes: []struct{ method, path, body string }{
+				{"PUT", "/orders/o1/status.json", `"done"`},
+				{"PUT", "/settings/currency.json", `"USD"`},
+				{"DELETE", "/orders/o2.json", ``},
+			},
+			want: []event{
+				{"put", `{"path":"/o1/status","data":"done"}`},
+				{"put", `{"path":"/o2","data":null}`},
+			},
+		},
+		{
+			name:   "put above the listen sends the whole value",
+			stream: "/orders/o1.json",
+			writes: []struct{ method, path, body string }{
+				{"PUT", "/orders.json", `{"o1":{"status":"done"}}`},
+			},
+			want: []event{
+				{"put", `{"path":"/","data":{"status":"done"}}`},
+			},
+		},
+		{
+			name:   "patch at or below the listen",
+			stream: "/orders.json",
+			writes: []struct{ method, path, body string }{
+				{"PATCH", "/orders.json", `{"o1/status":"done","o3":{"total":5}}`},
+				{"PATCH", "/orders/o2.json", `{"total":250}`},
+			},
+			want: []event{
+				{"patch", `{"path":"/","data":{"o1/status":"done","o3":{"total":5}}}`},
+				{"patch", `{"path":"/o2","data":{"total":250}}`},
+			},
+		},
+		{
+			name:   "patch above the listen",
+			stream: "/orders/o1.json",
+			writes: []struct{ method, path, body string }{
+				{"PATCH", "/.json", `{"orders/o1/status":"done","settings/currency":"USD"}`},
+			},
+			want: []event{
+				{"put", `{"path":"/status","data":"done"}`},
+			},
+		},
+		{
+			name:   "query sends its whole result again",
+			stream: `/orders.json?orderBy="total"&limitToFirst=1`,
+			writes: []struct{ method, path, body string }{
+				{"PUT", "/orders/o3.json", `{"total":50}`},
+			},
+			want: []event{
+				{"put", `{"path":"/","data":{"o3":{"total":50}}}`},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := newDatabase(seedTree())
+			if err != nil {
+				t.Fatalf("newDatabase: %v", err)
+			}
+			server := httptest.NewServer(db)
+			defer server.Close()
+
+			request, _ := http.NewRequest("GET", server.URL+test.stream, nil)
+			request.Header.Set("Accept", "text/event-stream")
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatalf("GET %s: %v", test.stream, err)
+			}
+			defer response.Body.Close()
+			reader := bufio.NewReader(response.Body)
+			next := func() event {
+				t.Helper()
+				var e event
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						t.Fatalf("read event: %v", err)
+					}
+					line = strings.TrimSuffix(line, "\n")
+					switch {
+					case line == "":
+						return e
+					case strings.HasPrefix(line, "event: "):
+						e.name = strings.TrimPrefix(line, "event: ")
+					case strings.HasPrefix(line, "data: "):
+						e.data = strings.TrimPrefix(line, "data: ")
+					}
+				}
+			}
+			first := next()
+			if data, _ := decodeJSON(t, first.data).(map[string]any); first.name != "put" || data["path"] != "/" {
+				t.Fatalf("first event = %s %s, want a put of the whole value", first.name, first.data)
+			}
+
+			for _, write := range test.writes {
+				request, _ := http.NewRequest(write.method, server.URL+write.path, strings.NewReader(write.body))
+				response, err := http.DefaultClient.Do(request)
+				if err != nil {
+					t.Fatalf("%s %s: %v", write.method, write.path, err)
+				}
+				response.Body.Close()
+			}
+			for _, want := range test.want {
+				got := next()
+				if got.name != want.name || !reflect.DeepEqual(decodeJSON(t, got.data), decodeJSON(t, want.data)) {
+					t.Errorf("event = %s %s, want %s %s", got.name, got.data, want.name, want.data)
+				}
+			}
+		})
+	}
+}
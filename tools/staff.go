@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Member lifecycles cycle through this list within each role (and within each
+// department for workers). The first member of every group is a veteran, so
+// there is always someone active to assign work to.
+//
+//   - veteran: hired before the history window, still working
+//   - resigned: left part way through the history
+//   - new_hire: joined during the history, still working
+//   - inactive: account locked recently, e.g. for long leave
+var memberLifecycleScenarios = []string{"veteran", "resigned", "new_hire", "veteran", "inactive"}
+
+// Tenure is the period a member can be assigned work in.
+type Tenure struct {
+	HiredAt int64
+	// LeftAt is when the member resigned or the account was locked; zero while
+	// the member is still working.
+	LeftAt int64
+	// Resigned tells a resignation apart from a locked account.
+	Resigned bool
+}
+
+// planTenure places a member's employment on the timeline for a lifecycle scenario.
+func planTenure(scenario string, timeline *Timeline, now int64) Tenure {
+	start := timeline.Start()
+	span := now - start
+	switch scenario {
+	case "resigned":
+		return Tenure{
+			HiredAt:  randomHireTime(timeline),
+			LeftAt:   timeline.Between(start+span/4, now-span/10),
+			Resigned: true,
+		}
+	case "new_hire":
+		return Tenure{HiredAt: timeline.Between(start+span/3, now-span/20)}
+	case "inactive":
+		return Tenure{
+			HiredAt: randomHireTime(timeline),
+			LeftAt:  timeline.Between(now-span/10, now),
+		}
+	default:
+		return Tenure{HiredAt: randomHireTime(timeline)}
+	}
+}
+
+// applyTenure sets the fields hr/members shows for a member's employment.
+// The member must be filled in afterwards so StartDate follows CreatedAt.
+func applyTenure(member *Member, tenure Tenure) {
+	member.CreatedAt = tenure.HiredAt
+	member.UpdatedAt = tenure.HiredAt
+	member.IsActive = tenure.LeftAt == 0
+	if tenure.LeftAt == 0 {
+		return
+	}
+	member.UpdatedAt = tenure.LeftAt
+	leftOn := time.UnixMilli(tenure.LeftAt).In(vietnamTime).Format("02/01/2006")
+	if tenure.Resigned {
+		member.Notes = fmt.Sprintf("Nghỉ việc từ %s", leftOn)
+	} else {
+		member.Notes = fmt.Sprintf("Tạm khóa tài khoản từ %s", leftOn)
+	}
+}
+
+// tenureOf reads back the employment period applyTenure recorded on a member,
+// for members loaded from an existing dataset.
+func tenureOf(member Member) Tenure {
+	tenure := Tenure{HiredAt: member.CreatedAt}
+	if !member.IsActive {
+		tenure.LeftAt = max(member.UpdatedAt, member.CreatedAt)
+		tenure.Resigned = strings.HasPrefix(member.Notes, "Nghỉ việc")
+	}
+	return tenure
+}
+
+// Roster answers who was on staff at a given time.
+type Roster struct {
+	members map[string]Member
+	tenures map[string]Tenure
+	ids     []string
+}
+
+func newRoster(members map[string]Member, tenures map[string]Tenure) *Roster {
+	ids := make([]string, 0, len(members))
+	for id := range members {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return &Roster{members: members, tenures: tenures, ids: ids}
+}
+
+// Tenure returns a member's employment period.
+func (r *Roster) Tenure(id string) Tenure {
+	return r.tenures[id]
+}
+
+// ActiveAt reports whether a member was employed at the given time.
+func (r *Roster) ActiveAt(id string, at int64) bool {
+	tenure, ok := r.tenures[id]
+	return ok && at >= tenure.HiredAt && (tenure.LeftAt == 0 || at < tenure.LeftAt)
+}
+
+// Active lists, in code order, the members matching match who were employed at the given time.
+func (r *Roster) Active(at int64, match func(Member) bool) []string {
+	var ids []string
+	for _, id := range r.ids {
+		if match(r.members[id]) && r.ActiveAt(id, at) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Pick returns a random member matching match who was employed at the given
+// time, or "" when there is none.
+func (r *Roster) Pick(at int64, match func(Member) bool) string {
+	ids := r.Active(at, match)
+	if len(ids) == 0 {
+		return ""
+	}
+	return ids[rand.Intn(len(ids))]
+}
+
+// Assign keeps preferred, usually the member who created the order, when they
+// were still employed at the given time and hands the work to someone else
+// otherwise.
+func (r *Roster) Assign(at int64, preferred string, match func(Member) bool) string {
+	if r.ActiveAt(preferred, at) {
+		return preferred
+	}
+	return r.Pick(at, match)
+}
+
+// Name returns a member's name, or "" for an unassigned ID.
+func (r *Roster) Name(id string) string {
+	return r.members[id].Name
+}
+
+func hasRole(role string) func(Member) bool {
+	return func(member Member) bool {
+		return member.Role == role
+	}
+}
+
+func inDepartment(deptCode string) func(Member) bool {
+	return func(member Member) bool {
+		return member.Role == "worker" && slices.Contains(member.Departments, deptCode)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const dayMillis = int64(24 * 3600 * 1000)
+
+// vietnamTime is ICT (UTC+7). Vietnam has no daylight saving, so a fixed zone
+// avoids depending on the host's tzdata.
+var vietnamTime = time.FixedZone("ICT", 7*3600)
+
+// TimeModel describes when customers come in: busier weekends, opening hours
+// with afternoon and evening peaks, and the holiday calendar below.
+type TimeModel struct {
+	// WeekdayWeights is indexed by time.Weekday (Sunday first).
+	WeekdayWeights [7]int
+	OpenHour       int
+	CloseHour      int
+	// HourWeights has one weight per opening hour, starting at OpenHour.
+	HourWeights []int
+}
+
+var defaultTimeModel = TimeModel{
+	WeekdayWeights: [7]int{130, 70, 80, 90, 90, 110, 150},
+	OpenHour:       9,
+	CloseHour:      21,
+	HourWeights:    []int{4, 7, 8, 5, 4, 6, 7, 8, 10, 11, 9, 5},
+}
+
+// First day of Tết (mùng 1) by year; the lunar calendar has no closed form.
+var tetDates = map[int]string{
+	2023: "2023-01-22",
+	2024: "2024-02-10",
+	2025: "2025-01-29",
+	2026: "2026-02-17",
+	2027: "2027-02-06",
+	2028: "2028-01-26",
+	2029: "2029-02-13",
+	2030: "2030-02-03",
+}
+
+// Around Tết customers rush to have bags and shoes cleaned before the holiday,
+// the shop closes from the eve to mùng 5, and business is slow afterwards.
+const (
+	tetRushDays    = 21
+	tetRushPercent = 250
+	tetClosedFrom  = -1
+	tetClosedTo    = 4
+	tetSlowDays    = 10
+	tetSlowPercent = 60
+)
+
+// Public holidays the shop is closed on.
+var closedHolidays = []struct {
+	Month time.Month
+	Day   int
+}{
+	{time.January, 1},
+	{time.April, 30},
+	{time.May, 1},
+	{time.September, 2},
+}
+
+// Gift-giving dates and long weekends raise demand in the days leading up to them.
+var seasonalPeaks = []struct {
+	Name     string
+	Month    time.Month
+	Day      int
+	LeadDays int
+	Percent  int
+}{
+	{"Valentine", time.February, 14, 7, 130},
+	{"Quốc tế Phụ nữ", time.March, 8, 7, 130},
+	{"Giải phóng miền Nam", time.April, 30, 7, 140},
+	{"Quốc khánh", time.September, 2, 7, 140},
+	{"Phụ nữ Việt Nam", time.October, 20, 7, 130},
+	{"Cuối năm", time.December, 31, 16, 130},
+}
+
+// Timeline draws event timestamps from a TimeModel over the history window
+// ending at the generation time.
+type Timeline struct {
+	model TimeModel
+	start int64
+	end   int64
+	tet   []time.Time
+}
+
+func newTimeline(model TimeModel, end time.Time, days int) *Timeline {
+	t := &Timeline{
+		model: model,
+		start: end.AddDate(0, 0, -days).UnixMilli(),
+		end:   end.UnixMilli(),
+	}
+	for _, date := range tetDates {
+		day, _ := time.ParseInLocation("2006-01-02", date, vietnamTime)
+		t.tet = append(t.tet, day)
+	}
+	return t
+}
+
+// Start is the beginning of the history window.
+func (t *Timeline) Start() int64 {
+	return t.start
+}
+
+// Random returns a business-hours timestamp within the history window.
+func (t *Timeline) Random() int64 {
+	return t.Between(t.start, t.end)
+}
+
+// Following returns a business-hours timestamp up to maxDays after from,
+// never later than the generation time.
+func (t *Timeline) Following(from int64, maxDays int) int64 {
+	return t.Between(from, min(from+int64(maxDays)*dayMillis, t.end))
+}
+
+// After returns a business-hours timestamp between minDays and maxDays after
+// from. Unlike Following it may fall in the future, as appointments do.
+func (t *Timeline) After(from int64, minDays, maxDays int) int64 {
+	return t.Between(from+int64(minDays)*dayMillis, from+int64(maxDays)*dayMillis)
+}
+
+// Between picks a day in [from, to] by its weight, then an opening hour by
+// HourWeights. It falls back to a uniform time when the range holds no
+// opening hours at all, such as a few hours overnight.
+func (t *Timeline) Between(from, to int64) int64 {
+	if to <= from {
+		return from
+	}
+
+	type slot struct {
+		from, to int64
+	}
+	var slots []slot
+	var cumulative []int
+	total := 0
+
+	day := startOfDay(from)
+	for day.UnixMilli() <= to {
+		dayWeight := t.dayWeight(day)
+		for h := t.model.OpenHour; dayWeight > 0 && h < t.model.CloseHour; h++ {
+			slotFrom := max(day.Add(time.Duration(h)*time.Hour).UnixMilli(), from)
+			slotTo := min(day.Add(time.Duration(h+1)*time.Hour).UnixMilli(), to)
+			if slotTo <= slotFrom {
+				continue
+			}
+			weight := dayWeight * t.model.HourWeights[h-t.model.OpenHour]
+			total += weight
+			slots = append(slots, slot{slotFrom, slotTo})
+			cumulative = append(cumulative, total)
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+
+	if total == 0 {
+		return from + rand.Int63n(to-from)
+	}
+	picked := slots[sort.SearchInts(cumulative, rand.Intn(total)+1)]
+	return picked.from + rand.Int63n(picked.to-picked.from)
+}
+
+// dayWeight is the relative number of customers expected on a day, 0 when closed.
+func (t *Timeline) dayWeight(day time.Time) int {
+	weight := t.model.WeekdayWeights[day.Weekday()]
+
+	for _, tet := range t.tet {
+		offset := int(day.Sub(tet).Hours() / 24)
+		switch {
+		case offset >= tetClosedFrom && offset <= tetClosedTo:
+			return 0
+		case offset < tetClosedFrom && offset >= tetClosedFrom-tetRushDays:
+			return weight * tetRushPercent / 100
+		case offset > tetClosedTo && offset <= tetClosedTo+tetSlowDays:
+			return weight * tetSlowPercent / 100
+		}
+	}
+
+	for _, holiday := range closedHolidays {
+		if day.Month() == holiday.Month && day.Day() == holiday.Day {
+			return 0
+		}
+	}
+	for _, peak := range seasonalPeaks {
+		peakDay := time.Date(day.Year(), peak.Month, peak.Day, 0, 0, 0, 0, vietnamTime)
+		if lead := int(peakDay.Sub(day).Hours() / 24); lead > 0 && lead <= peak.LeadDays {
+			return weight * peak.Percent / 100
+		}
+	}
+	return weight
+}
+
+func startOfDay(ms int64) time.Time {
+	local := time.UnixMilli(ms).In(vietnamTime)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, vietnamTime)
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// vocabVersion is the pack format this generator understands. Bump it when a
+// field is renamed or its meaning changes, so stale packs fail loudly.
+const vocabVersion = 1
+
+const defaultVocabName = "default"
+
+//go:embed vocab/*.json
+var builtinVocabs embed.FS
+
+// Vocabulary is a pack of word lists and catalogues used to generate data.
+// Packs are JSON files with the layout of vocab/default.json.
+type Vocabulary struct {
+	Version     int    `json:"version"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	Names VocabNames `json:"names"`
+
+	// Departments are listed, and each one's workflowNames, in the order
+	// an item goes through them.
+	Departments   []VocabDepartment   `json:"departments"`
+	WorkflowNames map[string][]string `json:"workflowNames"`
+	ProductTypes  []VocabProductType  `json:"productTypes"`
+
+	CategoryNames        []string        `json:"categoryNames"`
+	CategoryColors       []string        `json:"categoryColors"`
+	Materials            []VocabMaterial `json:"materials"`
+	PerishableCategories []string        `json:"perishableCategories,omitempty"`
+	SupplierNames        []string        `json:"supplierNames"`
+	WarehouseNames       []string        `json:"warehouseNames"`
+
+	// AddressWeights weights the provinces of data/admin_units.json by
+	// code, sharing the customers out between the workshop's city and the
+	// cities they send items in from.
+	AddressWeights map[string]int `json:"addressWeights,omitempty"`
+	// Branches are the payrollBranch and workingBranches values members get,
+	// the first being everyone's payroll branch. They must be branches the
+	// selects on hr/members offer.
+	Branches []string `json:"branches"`
+
+	OperationalTaskNames []string `json:"operationalTaskNames"`
+	StandaloneTaskTitles []string `json:"standaloneTaskTitles"`
+	CancelReasons        []string `json:"cancelReasons"`
+
+	OrderHoldReasons   []string `json:"orderHoldReasons"`
+	OrderCancelReasons []string `json:"orderCancelReasons"`
+
+	ManualFinanceEntries []VocabFinanceEntry `json:"manualFinanceEntries"`
+}
+
+// VocabNames models Vietnamese full names: a surname weighted by how common it
+// is, then a middle and a given name chosen for the person's gender.
+type VocabNames struct {
+	Surnames    []WeightedName            `json:"surnames"`
+	MiddleNames map[string][]WeightedName `json:"middleNames"`
+	GivenNames  map[string][]WeightedName `json:"givenNames"`
+}
+
+type WeightedName struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+type VocabDepartment struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// VocabProductType groups the items customers bring in with the services
+// offered for them. Services carry the product type's own list price, so the
+// same service can cost more on a bag than on a pair of shoes.
+type VocabProductType struct {
+	Code     string         `json:"code"`
+	Name     string         `json:"name"`
+	Items    []string       `json:"items"`
+	Services []VocabService `json:"services"`
+}
+
+// VocabService is a price-list entry; Price is the list price in VND.
+type VocabService struct {
+	Name  string `json:"name"`
+	Price int    `json:"price"`
+}
+
+// VocabMaterial is a material in stock. ImportPrice is per unit; quantities
+// are in units too: TypicalImport is what one purchase brings in and
+// ReorderLevel the stock at which the workshop buys again.
+type VocabMaterial struct {
+	Name          string `json:"name"`
+	Category      string `json:"category"`
+	Unit          string `json:"unit"`
+	ImportPrice   int    `json:"importPrice"`
+	TypicalImport int    `json:"typicalImport"`
+	ReorderLevel  int    `json:"reorderLevel"`
+}
+
+// VocabFinanceEntry is a kind of entry staff add by hand on the finance page.
+// Category is one of the finance page's categories.
+type VocabFinanceEntry struct {
+	Type        string `json:"type"`
+	Category    string `json:"category"`
+	Description string `json:"description"`
+	MinAmount   int    `json:"minAmount"`
+	MaxAmount   int    `json:"maxAmount"`
+}
+
+// Word lists read by the generator, set from the active pack by applyVocabulary.
+var (
+	personNames VocabNames
+
+	departments   []VocabDepartment
+	workflowNames map[string][]string
+	productTypes  []VocabProductType
+
+	categoryNames        []string
+	categoryColors       []string
+	materialNames        []string
+	materialCategories   map[string]string
+	materialUnits        map[string]string
+	materialPrices       map[string]int
+	materialImports      map[string]int
+	materialReorder      map[string]int
+	perishableCategories map[string]bool
+	supplierNames        []string
+	warehouseNames       []string
+
+	branches []string
+
+	operationalTaskNames []string
+	standaloneTaskTitles []string
+	cancelReasons        []string
+
+	orderHoldReasons   []string
+	orderCancelReasons []string
+
+	manualFinanceEntries []VocabFinanceEntry
+)
+
+// loadVocabulary resolves a pack by name. Names of built-in packs ("default")
+// are read from the embedded vocab directory; anything else is a file path.
+func loadVocabulary(name string) (*Vocabulary, error) {
+	if name == "" {
+		name = defaultVocabName
+	}
+
+	raw, err := builtinVocabs.ReadFile("vocab/" + name + ".json")
+	if err != nil {
+		raw, err = os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("vocabulary %q is neither a built-in pack (%s) nor a readable file: %w",
+				name, strings.Join(builtinVocabNames(), ", "), err)
+		}
+	}
+
+	var vocab Vocabulary
+	if err := json.Unmarshal(raw, &vocab); err != nil {
+		return nil, fmt.Errorf("parse vocabulary %q: %w", name, err)
+	}
+	if err := vocab.Validate(); err != nil {
+		return nil, fmt.Errorf("vocabulary %q: %w", name, err)
+	}
+	return &vocab, nil
+}
+
+func builtinVocabNames() []string {
+	entries, _ := builtinVocabs.ReadDir("vocab")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+	}
+	return names
+}
+
+// Validate checks that the pack is complete and that its cross-references resolve.
+func (v *Vocabulary) Validate() error {
+	if v.Version != vocabVersion {
+		return fmt.Errorf("unsupported version %d (expected %d)", v.Version, vocabVersion)
+	}
+
+	required := map[string]int{
+		"departments":          len(v.Departments),
+		"productTypes":         len(v.ProductTypes),
+		"categoryNames":        len(v.CategoryNames),
+		"categoryColors":       len(v.CategoryColors),
+		"materials":            len(v.Materials),
+		"supplierNames":        len(v.SupplierNames),
+		"warehouseNames":       len(v.WarehouseNames),
+		"branches":             len(v.Branches),
+		"operationalTaskNames": len(v.OperationalTaskNames),
+		"standaloneTaskTitles": len(v.StandaloneTaskTitles),
+		"cancelReasons":        len(v.CancelReasons),
+		"orderHoldReasons":     len(v.OrderHoldReasons),
+		"orderCancelReasons":   len(v.OrderCancelReasons),
+		"manualFinanceEntries": len(v.ManualFinanceEntries),
+	}
+	for field, n := range required {
+		if n == 0 {
+			return fmt.Errorf("%s must not be empty", field)
+		}
+	}
+
+	if err := validateWeightedNames("names.surnames", v.Names.Surnames); err != nil {
+		return err
+	}
+	for _, field := range []struct {
+		Name     string
+		ByGender map[string][]WeightedName
+	}{
+		{"names.middleNames", v.Names.MiddleNames},
+		{"names.givenNames", v.Names.GivenNames},
+	} {
+		for _, gender := range genders {
+			if err := validateWeightedNames(field.Name+"."+gender, field.ByGender[gender]); err != nil {
+				return err
+			}
+		}
+		if len(field.ByGender) != len(genders) {
+			return fmt.Errorf("%s must only have the genders %s", field.Name, strings.Join(genders, ", "))
+		}
+	}
+	// randomName draws given names until one differs from the middle name
+	for _, gender := range genders {
+		for _, middle := range v.Names.MiddleNames[gender] {
+			if !slices.ContainsFunc(v.Names.GivenNames[gender], func(given WeightedName) bool { return given.Name != middle.Name }) {
+				return fmt.Errorf("names.givenNames.%s has no name other than the middle name %q", gender, middle.Name)
+			}
+		}
+	}
+
+	deptCodes := make(map[string]bool)
+	for _, dept := range v.Departments {
+		if dept.Code == "" || dept.Name == "" {
+			return fmt.Errorf("department %+v needs a code and a name", dept)
+		}
+		if deptCodes[dept.Code] {
+			return fmt.Errorf("duplicate department code %q", dept.Code)
+		}
+		deptCodes[dept.Code] = true
+		if len(v.WorkflowNames[dept.Code]) == 0 {
+			return fmt.Errorf("department %q has no workflowNames", dept.Code)
+		}
+	}
+	for deptCode := range v.WorkflowNames {
+		if !deptCodes[deptCode] {
+			return fmt.Errorf("workflowNames references unknown department %q", deptCode)
+		}
+	}
+
+	for _, productType := range v.ProductTypes {
+		if len(productType.Items) == 0 || len(productType.Services) == 0 {
+			return fmt.Errorf("product type %q needs items and services", productType.Code)
+		}
+		for _, service := range productType.Services {
+			if service.Name == "" || service.Price <= 0 {
+				return fmt.Errorf("product type %q has service %+v; services need a name and a positive price", productType.Code, service)
+			}
+		}
+	}
+
+	categories := make(map[string]bool)
+	for _, category := range v.CategoryNames {
+		categories[category] = true
+	}
+	validUnits := make(map[string]bool)
+	for _, unit := range units {
+		validUnits[unit] = true
+	}
+	materials := make(map[string]bool)
+	for _, material := range v.Materials {
+		if materials[material.Name] {
+			return fmt.Errorf("duplicate material %q", material.Name)
+		}
+		materials[material.Name] = true
+		if !categories[material.Category] {
+			return fmt.Errorf("material %q references unknown category %q", material.Name, material.Category)
+		}
+		if !validUnits[material.Unit] {
+			return fmt.Errorf("material %q has unit %q, which is not in enum.ts", material.Name, material.Unit)
+		}
+		if material.ImportPrice <= 0 {
+			return fmt.Errorf("material %q needs a positive importPrice", material.Name)
+		}
+		if material.TypicalImport <= 0 || material.ReorderLevel <= 0 {
+			return fmt.Errorf("material %q needs a positive typicalImport and reorderLevel", material.Name)
+		}
+	}
+	for _, category := range v.PerishableCategories {
+		if !categories[category] {
+			return fmt.Errorf("perishableCategories references unknown category %q", category)
+		}
+	}
+	for _, entry := range v.ManualFinanceEntries {
+		if !slices.Contains(financeTypes, entry.Type) || !slices.Contains(financeCategories, entry.Category) {
+			return fmt.Errorf("manual finance entry %q needs a type in %s and a category in %s",
+				entry.Description, strings.Join(financeTypes, ", "), strings.Join(financeCategories, ", "))
+		}
+		if entry.Description == "" || entry.MinAmount <= 0 || entry.MaxAmount < entry.MinAmount {
+			return fmt.Errorf("manual finance entry %+v needs a description and 0 < minAmount <= maxAmount", entry)
+		}
+	}
+	return nil
+}
+
+func validateWeightedNames(field string, names []WeightedName) error {
+	if len(names) == 0 {
+		return fmt.Errorf("%s must not be empty", field)
+	}
+	for _, name := range names {
+		if name.Name == "" || name.Weight <= 0 {
+			return fmt.Errorf("%s has entry %+v; names need a value and a positive weight", field, name)
+		}
+	}
+	return nil
+}
+
+// applyVocabulary makes the pack the source of the generator's word lists.
+func applyVocabulary(v *Vocabulary) {
+	personNames = v.Names
+
+	departments = v.Departments
+	workflowNames = v.WorkflowNames
+	productTypes = v.ProductTypes
+
+	categoryNames = v.CategoryNames
+	categoryColors = v.CategoryColors
+	materialNames = make([]string, 0, len(v.Materials))
+	materialCategories = make(map[string]string, len(v.Materials))
+	materialUnits = make(map[string]string, len(v.Materials))
+	materialPrices = make(map[string]int, len(v.Materials))
+	materialImports = make(map[string]int, len(v.Materials))
+	materialReorder = make(map[string]int, len(v.Materials))
+	for _, material := range v.Materials {
+		materialNames = append(materialNames, material.Name)
+		materialCategories[material.Name] = material.Category
+		materialUnits[material.Name] = material.Unit
+		materialPrices[material.Name] = material.ImportPrice
+		materialImports[material.Name] = material.TypicalImport
+		materialReorder[material.Name] = material.ReorderLevel
+	}
+	perishableCategories = make(map[string]bool, len(v.PerishableCategories))
+	for _, category := range v.PerishableCategories {
+		perishableCategories[category] = true
+	}
+	supplierNames = v.SupplierNames
+	warehouseNames = v.WarehouseNames
+
+	branches = v.Branches
+
+	operationalTaskNames = v.OperationalTaskNames
+	standaloneTaskTitles = v.StandaloneTaskTitles
+	cancelReasons = v.CancelReasons
+
+	orderHoldReasons = v.OrderHoldReasons
+	orderCancelReasons = v.OrderCancelReasons
+
+	manualFinanceEntries = v.ManualFinanceEntries
+}
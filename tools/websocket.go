@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// The server side of a WebSocket connection (RFC 6455), with what the wire
+// protocol needs: text messages, whole or in fragments, ping, pong and close.
+// No extension, such as permessage-deflate, is negotiated.
+
+// websocketGUID is appended to the client's key to prove the upgrade.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxMessageSize bounds a message from the client, fragments included.
+const maxMessageSize = 64 << 20
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+type wsConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// mu keeps frames written from different goroutines whole.
+	mu sync.Mutex
+}
+
+// upgradeWebSocket answers a WebSocket handshake and takes the connection
+// over from the HTTP server. On failure it has written the error response.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+		!headerContains(r.Header, "Connection", "upgrade") || key == "" {
+		writeError(w, http.StatusBadRequest, "expected a WebSocket upgrade")
+		return nil, fmt.Errorf("not a WebSocket upgrade")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		writeError(w, http.StatusUpgradeRequired, "unsupported WebSocket version")
+		return nil, fmt.Errorf("unsupported WebSocket version")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "connection cannot be upgraded")
+		return nil, fmt.Errorf("connection cannot be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(sum[:]))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, reader: rw.Reader}, nil
+}
+
+// headerContains reports whether a comma-separated header lists token.
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage returns the next text or binary message, answering pings and
+// skipping pongs on the way. A close from the client is answered and ends
+// the connection with io.EOF.
+func (c *wsConn) ReadMessage() ([]byte, error) {
+	var message []byte
+	fragmented := false
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case opClose:
+			// Echo the status code, if any, as the closing handshake asks.
+			c.writeFrame(opClose, payload[:min(len(payload), 2)])
+			return nil, io.EOF
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opText, opBinary:
+			if fragmented {
+				return nil, fmt.Errorf("new message before the last one ended")
+			}
+			message = payload
+		case opContinuation:
+			if !fragmented {
+				return nil, fmt.Errorf("continuation frame without a message")
+			}
+			message = append(message, payload...)
+		default:
+			return nil, fmt.Errorf("unknown opcode %#x", opcode)
+		}
+		if len(message) > maxMessageSize {
+			return nil, fmt.Errorf("message exceeds %d bytes", maxMessageSize)
+		}
+		if fin {
+			return message, nil
+		}
+		fragmented = true
+	}
+}
+
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(c.reader, header[:]); err != nil {
+		return
+	}
+	fin, opcode = header[0]&0x80 != 0, header[0]&0x0f
+	if header[1]&0x80 == 0 {
+		err = fmt.Errorf("client frames must be masked")
+		return
+	}
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err = io.ReadFull(c.reader, extended[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err = io.ReadFull(c.reader, extended[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if length > maxMessageSize {
+		err = fmt.Errorf("frame exceeds %d bytes", maxMessageSize)
+		return
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(c.reader, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.reader, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// WriteText sends a text message in a single frame.
+func (c *wsConn) WriteText(message []byte) error {
+	return c.writeFrame(opText, message)
+}
+
+// writeFrame writes one final, unmasked frame, as servers send them.
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The Realtime Database wire protocol, as the client SDKs speak it over a
+// WebSocket at /.ws. The server opens with a handshake; then the client sends
+// requests {r, a, b}, a number, an action and its body, answered with
+// {r, b: {s, d}}, a status and data, and the server pushes the data of the
+// listens the client holds as {a: "d" or "m", b: {p, d, t}}: a put or merge
+// at a path, for the listen with tag t. Every message is wrapped as
+// {t: "d", d: ...}, or {t: "c", d: ...} for the connection's own control
+// messages. A message too long for one frame is sent as the number of
+// frames, then the frames.
+//
+// Like the REST side, the server enforces no rules: it accepts any
+// credential it is sent and every read and write.
+
+// wireProtocolVersion is the version of the protocol the handshake reports.
+const wireProtocolVersion = "5"
+
+// wireSession is a client connection.
+type wireSession struct {
+	db   *Database
+	conn *wsConn
+	out  chan []byte
+
+	// closed and onDisconnect are guarded by db.mu. onDisconnect holds the
+	// writes the client asked for when it goes away.
+	closed       bool
+	onDisconnect []wireWrite
+
+	// frames counts down the frames of a message sent in several, and parts
+	// holds those received so far.
+	frames int
+	parts  []byte
+}
+
+// wireWrite is an onDisconnect write: a put or, with merge, a merge.
+type wireWrite struct {
+	path  []string
+	data  any
+	merge bool
+}
+
+// wireRequest is a request from the client.
+type wireRequest struct {
+	Number json.RawMessage `json:"r"`
+	Action string          `json:"a"`
+	Body   map[string]any  `json:"b"`
+}
+
+// serveWire runs a wire protocol connection until the client goes away.
+func (d *Database) serveWire(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	s := &wireSession{db: d, conn: conn, out: make(chan []byte, listenerBuffer)}
+	go s.write()
+
+	d.mu.Lock()
+	s.queue(map[string]any{"t": "c", "d": map[string]any{"t": "h", "d": map[string]any{
+		"ts": time.Now().UnixMilli(),
+		"v":  wireProtocolVersion,
+		"h":  r.Host,
+		"s":  strconv.FormatInt(rand.Int63(), 36),
+	}}})
+	d.mu.Unlock()
+
+	for {
+		message, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		s.receive(message)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s.end()
+	now := time.Now().UnixMilli()
+	for _, write := range s.onDisconnect {
+		if write.merge {
+			d.merge(write.path, write.data.(map[string]any), now)
+		} else {
+			d.put(write.path, write.data, now)
+		}
+	}
+}
+
+// write sends the queued messages until the session ends. Once a write
+// fails it closes the connection, which ends the session, and drops the rest.
+func (s *wireSession) write() {
+	failed := false
+	for message := range s.out {
+		if !failed && s.conn.WriteText(message) != nil {
+			failed = true
+			s.conn.Close()
+		}
+	}
+	s.conn.Close()
+}
+
+// queue sends a message, ending the session when the client has fallen too
+// far behind: it reconnects and listens again. Callers hold db.mu.
+func (s *wireSession) queue(message any) {
+	if s.closed {
+		return
+	}
+	raw, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	select {
+	case s.out <- raw:
+	default:
+		s.end()
+	}
+}
+
+// end stops the session's listens and lets the writer finish. Callers hold
+// db.mu.
+func (s *wireSession) end() {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	for l := range s.db.listeners {
+		if l.session == s {
+			delete(s.db.listeners, l)
+		}
+	}
+	close(s.out)
+}
+
+// push sends the data of a listen: a put event as a "d" and a patch as an
+// "m", at the listen's path joined with path. Callers hold db.mu.
+func (s *wireSession) push(l *listener, event string, path []string, data any) {
+	action := "d"
+	if event == "patch" {
+		action = "m"
+	}
+	body := map[string]any{
+		"p": strings.Join(append(append([]string(nil), l.path...), path...), "/"),
+		"d": data,
+	}
+	if l.tag != 0 {
+		body["t"] = l.tag
+	}
+	s.queue(map[string]any{"t": "d", "d": map[string]any{"a": action, "b": body}})
+}
+
+// respond answers request number r. Callers hold db.mu.
+func (s *wireSession) respond(r json.RawMessage, status string, data any) {
+	s.queue(map[string]any{"t": "d", "d": map[string]any{"r": r, "b": map[string]any{"s": status, "d": data}}})
+}
+
+// receive handles a message from the client, putting messages sent in
+// several frames back together first.
+func (s *wireSession) receive(message []byte) {
+	if s.frames > 0 {
+		s.parts = append(s.parts, message...)
+		if s.frames--; s.frames > 0 {
+			return
+		}
+		message, s.parts = s.parts, nil
+	} else if len(message) <= 6 {
+		// A frame count, or "0", the client's keep-alive.
+		if n, err := strconv.Atoi(string(message)); err == nil {
+			s.frames = n
+			return
+		}
+	}
+
+	var envelope struct {
+		Type string          `json:"t"`
+		Data json.RawMessage `json:"d"`
+	}
+	if json.Unmarshal(message, &envelope) != nil {
+		return
+	}
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	switch envelope.Type {
+	case "c":
+		var control struct {
+			Type string `json:"t"`
+		}
+		if json.Unmarshal(envelope.Data, &control) == nil && control.Type == "p" {
+			s.queue(map[string]any{"t": "c", "d": map[string]any{"t": "o", "d": map[string]any{}}})
+		}
+	case "d":
+		var request wireRequest
+		if json.Unmarshal(envelope.Data, &request) == nil {
+			s.handle(request)
+		}
+	}
+}
+
+// handle carries out a request and answers it. Callers hold db.mu.
+func (s *wireSession) handle(request wireRequest) {
+	d := s.db
+	body := request.Body
+	ok := func(data any) { s.respond(request.Number, "ok", data) }
+	fail := func(status string, err error) { s.respond(request.Number, status, err.Error()) }
+
+	var path []string
+	if p, has := body["p"].(string); has {
+		var err error
+		if path, err = parsePath(p); err != nil {
+			fail("invalid_path", err)
+			return
+		}
+	}
+	query := func() (*Query, error) {
+		object, _ := body["q"].(map[string]any)
+		return parseWireQuery(object)
+	}
+	tag, _ := body["t"].(float64)
+	now := time.Now().UnixMilli()
+
+	switch request.Action {
+	case "s", "auth", "gauth", "unauth", "appcheck", "unappcheck":
+		// Stats, credentials and App Check tokens are taken as they come.
+		ok(map[string]any{})
+	case "q":
+		q, err := query()
+		if err != nil {
+			fail("invalid_query", err)
+			return
+		}
+		l := &listener{path: path, query: q, session: s, tag: tag}
+		d.listeners[l] = true
+		// The data goes first: an answer before it would tell the client
+		// the location is empty.
+		d.send(l, "put", nil, d.read(path, q, false))
+		ok(map[string]any{})
+	case "n":
+		for l := range d.listeners {
+			if l.session == s && l.tag == tag && slices.Equal(l.path, path) {
+				delete(d.listeners, l)
+			}
+		}
+		ok(map[string]any{})
+	case "g":
+		q, err := query()
+		if err != nil {
+			fail("invalid_query", err)
+			return
+		}
+		ok(d.read(path, q, false))
+	case "p":
+		// A transaction's write carries the hash of the value it started
+		// from; the client retries when the value has changed since.
+		if hash, has := body["h"].(string); has && hash != nodeHash(getAt(d.root, path)) {
+			s.respond(request.Number, "datastale", "the value changed before the transaction was written")
+			return
+		}
+		if _, err := d.put(path, body["d"], now); err != nil {
+			fail("invalid_data", err)
+			return
+		}
+		ok("")
+	case "m":
+		updates, isObject := body["d"].(map[string]any)
+		if !isObject {
+			fail("invalid_data", fmt.Errorf("a merge must be an object"))
+			return
+		}
+		if err := d.merge(path, updates, now); err != nil {
+			fail("invalid_data", err)
+			return
+		}
+		ok("")
+	case "o", "om":
+		merge := request.Action == "om"
+		if _, isObject := body["d"].(map[string]any); merge && !isObject {
+			fail("invalid_data", fmt.Errorf("a merge must be an object"))
+			return
+		}
+		s.onDisconnect = append(s.onDisconnect, wireWrite{path: path, data: body["d"], merge: merge})
+		ok("")
+	case "oc":
+		kept := s.onDisconnect[:0]
+		for _, write := range s.onDisconnect {
+			if !hasPathPrefix(write.path, path) {
+				kept = append(kept, write)
+			}
+		}
+		s.onDisconnect = kept
+		ok("")
+	default:
+		fail("invalid_request", fmt.Errorf("unsupported action %q", request.Action))
+	}
+}
+
+// nodeHash is the hash the client SDKs compute for a value and send with a
+// transaction's write: base64 of a SHA-1 over its type and contents, children
+// in key order. It must match theirs exactly, so numbers are encoded the way
+// their doubleToIEEE754String does it.
+func nodeHash(value any) string {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return compareKeys(keys[i], keys[j]) < 0 })
+		var b strings.Builder
+		for _, key := range keys {
+			if hash := nodeHash(v[key]); hash != "" {
+				b.WriteString(":" + key + ":" + hash)
+			}
+		}
+		if b.Len() == 0 {
+			return ""
+		}
+		text = b.String()
+	case bool:
+		text = "boolean:" + strconv.FormatBool(v)
+	case float64:
+		text = "number:" + doubleHex(v)
+	case string:
+		text = "string:" + v
+	default:
+		text = fmt.Sprintf("%T:%v", v, v)
+	}
+	sum := sha1.Sum([]byte(text))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// doubleHex follows the SDKs' doubleToIEEE754String, which works the exponent
+// out with a logarithm rather than reading the bits, and so can differ from
+// them by an exponent for some powers of two.
+func doubleHex(v float64) string {
+	const ebits, fbits = 11, 52
+	const bias = 1<<(ebits-1) - 1
+	var sign bool
+	var e, f float64
+	if v == 0 {
+		sign = math.Signbit(v)
+	} else {
+		sign = v < 0
+		v = math.Abs(v)
+		if v >= math.Ldexp(1, 1-bias) {
+			ln := math.Min(math.Floor(math.Log(v)/math.Ln2), bias)
+			e = ln + bias
+			f = math.Round(v*math.Ldexp(1, fbits-int(ln)) - math.Ldexp(1, fbits))
+		} else {
+			f = math.Round(v / math.Ldexp(1, 1-bias-fbits))
+		}
+	}
+	// Near the bottom of the range f overflows to infinity; its remainder is
+	// then NaN, which the JavaScript takes as a 0 bit.
+	var bits uint64
+	for i := 0; i < fbits; i++ {
+		if math.Abs(math.Mod(f, 2)) == 1 {
+			bits |= 1 << i
+		}
+		f = math.Floor(f / 2)
+	}
+	for i := 0; i < ebits; i++ {
+		if math.Abs(math.Mod(e, 2)) == 1 {
+			bits |= 1 << (fbits + i)
+		}
+		e = math.Floor(e / 2)
+	}
+	if sign {
+		bits |= 1 << 63
+	}
+	return fmt.Sprintf("%016x", bits)
+}
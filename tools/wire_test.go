@@ -0,0 +1,480 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wireClient speaks the wire protocol to a test server the way the client
+// SDKs do: masked text frames out, one message per frame back.
+type wireClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialWire(t *testing.T, server *httptest.Server) *wireClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	request := "GET /.ws?v=5&ns=demo HTTP/1.1\r\nHost: " + strings.TrimPrefix(server.URL, "http://") +
+		"\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(request)); err != nil {
+		t.Fatalf("write upgrade: %v", err)
+	}
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("read upgrade: %v", err)
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("upgrade status = %d, want 101", response.StatusCode)
+	}
+	// The example key and answer of RFC 6455, section 1.3.
+	if accept := response.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept = %q", accept)
+	}
+	c := &wireClient{t: t, conn: conn, reader: reader}
+	t.Cleanup(func() { conn.Close() })
+	return c
+}
+
+// sendFrame writes one masked frame.
+func (c *wireClient) sendFrame(fin bool, opcode byte, payload []byte) {
+	c.t.Helper()
+	header := []byte{opcode, 0x80}
+	if fin {
+		header[0] |= 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		header[1] |= byte(n)
+	case n <= 0xffff:
+		header[1] |= 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] |= 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	var mask [4]byte
+	rand.Read(mask[:])
+	masked := make([]byte, len(payload))
+	for i, b := range payload {
+		masked[i] = b ^ mask[i%4]
+	}
+	frame := append(append(header, mask[:]...), masked...)
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatalf("write frame: %v", err)
+	}
+}
+
+// send writes a message, in as many WebSocket fragments as asked.
+func (c *wireClient) send(message string, fragments int) {
+	c.t.Helper()
+	fragments = max(fragments, 1)
+	size := max((len(message)+fragments-1)/fragments, 1)
+	opcode := byte(opText)
+	for len(message) > size {
+		c.sendFrame(false, opcode, []byte(message[:size]))
+		message, opcode = message[size:], opContinuation
+	}
+	c.sendFrame(true, opcode, []byte(message))
+}
+
+// next reads the next frame from the server.
+func (c *wireClient) next() (opcode byte, payload []byte) {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		c.t.Fatalf("read frame: %v", err)
+	}
+	if header[1]&0x80 != 0 {
+		c.t.Fatalf("server frames must not be masked")
+	}
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var extended [2]byte
+		io.ReadFull(c.reader, extended[:])
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		io.ReadFull(c.reader, extended[:])
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		c.t.Fatalf("read frame: %v", err)
+	}
+	return header[0] & 0x0f, payload
+}
+
+// message reads the next message and decodes it.
+func (c *wireClient) message() any {
+	c.t.Helper()
+	opcode, payload := c.next()
+	if opcode != opText {
+		c.t.Fatalf("opcode = %#x, want a text frame", opcode)
+	}
+	var value any
+	if err := json.Unmarshal(payload, &value); err != nil {
+		c.t.Fatalf("server sent %s: %v", payload, err)
+	}
+	return value
+}
+
+// matchJSON reports whether got has the shape of want, where "*" in want
+// matches any value.
+func matchJSON(got, want any) bool {
+	switch w := want.(type) {
+	case string:
+		return w == "*" || got == w
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for key, value := range w {
+			if child, has := g[key]; !has || !matchJSON(child, value) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		g, ok := got.([]any)
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for i := range w {
+			if !matchJSON(g[i], w[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return got == want
+}
+
+func TestWireProtocol(t *testing.T) {
+	type step struct {
+		send      string
+		fragments int
+		want      []string
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "listen sends the data, then answers",
+			steps: []step{
+				{send: `{"t":"d","d":{"r":1,"a":"q","b":{"p":"/settings","h":""}}}`, want: []string{
+					`{"t":"d","d":{"a":"d","b":{"p":"settings","d":{"currency":"VND"}}}}`,
+					`{"t":"d","d":{"r":1,"b":{"s":"ok","d":{}}}}`,
+				}},
+			},
+		},
+		{
+			name: "put pushes to the listen",
+			steps: []step{
+				{send: `{"t":"d","d":{"r":1,"a":"q","b":{"p":"/orders","h":""}}}`, want: []string{`*`, `*`}},
+				{send: `{"t":"d","d":{"r":2,"a":"p","b":{"p":"/orders/o1/status","d":"done"}}}`, want: []string{
+					`{"t":"d","d":{"a":"d","b":{"p":"orders/o1/status","d":"done"}}}`,
+					`{"t":"d","d":{"r":2,"b":{"s":"ok","d":""}}}`,
+				}},
+				{send: `{"t":"d","d":{"r":3,"a":"p","b":{"p":"/settings/currency","d":"USD"}}}`, want: []string{
+					`{"t":"d","d":{"r":3,"b":{"s":"ok","d":""}}}`,
+				}},
+			},
+		},
+		{
+			name: "merge pushes a merge",
+			steps: []step{
+				{send: `{"t":"d","d":{"r":1,"a":"q","b":{"p":"/orders","h":""}}}`, want: []string{`*`, `*`}},
+				{send: `{"t":"d","d":{"r":2,"a":"m","b":{"p":"/orders","d":{"o1/status":"done","o3":{"total":5}}}}}`, want: []string{
+					`{"t":"d","d":{"a":"m","b":{"p":"orders","d":{"o1/status":"done","o3":{"total":5}}}}}`,
+					`{"t":"d","d":{"r":2,"b":{"s":"ok","d":""}}}`,
+				}},
+			},
+		},
+		{
+			name: "merge with an invalid key changes nothing",
+			steps: []step{
+				{send: `{"t":"d","d":{"r":1,"a":"m","b":{"p":"/orders","d":{"o1/status":"done","o1/total":1,"o2/status":"new","o2/total":2,"o3":{"total":3},"o2/a.b":1}}}}`, want: []string{
+					`{"t":"d","d":{"r":1,"b":{"s":"invalid_data","d":"*"}}}`,
+				}},
+				{send: `{"t":"d","d":{"r":2,"a":"g","b":{"p":"/orders/o1/status"}}}`, want: []string{
+					`{"t":"d","d":{"r":2,"b":{"s":"ok","d":"pending"}}}`,
+				}},
+			},
+		},
+		{
+			name: "query listen is tagged and sent whole",
+			steps: []step{
+				{send: `{"t":"d","d":{"r":1,"a":"q","b":{"p":"/orders","q":{"i":"total","l":1,"vf":"l"},"t":1,"h":""}}}`, want: []string{
+					`{"t":"d","d":{"a":"d","b":{"p":"orders","d":{"o1":{"status":"pending","total":100}},"t":1}}}`,
+					`{"t":"d","d":{"r":1,"b":{"s":"ok","d":{}}}}`,
+				}},
+				{send: `{"t":"d","d":{"r":2,"a":"p","b":{"p":"/orders/o3","d":{"total":50}}}}`, want: []string{
+					`{"t":"d","d":{"a":"d","b":{"p":"orders","d":{"o3":{"total":50}},"t":1}}}`,
+					`{"t":"d","d":{"r":2,"b":{"s":"ok","d":""}}}`,
+				}},
+			},
+		},
+		{
+			name: "unlisten stops the pushes",
+			steps: []step{
+				{send: `{"t":"d","d":{"r":1,"a":"q","b":{"p":"/orders","h":""}}}`, want: []string{`*`, `*`}},
+				{send: `{"t":"d","d":{"r":2,"a":"n","b":{"p":"/orders"}}}`, want: []string{
+					`{"t":"d","d":{"r":2,"b":{"s":"ok","d":{}}}}`,
+				}},
+				{send: `{"t":"d","d":{"r":3,"a":"p","b":{"p":"/orders/o1/status","d":"done"}}}`, want: []string{
+					`{"t":"d","d":{"r":3,"b":{"s":"ok","d":""}}}`,
+				}},
+			},
+		},
+		{
+			name: "get",
+			steps: []step{
+				{send: `{"t":"d","d":{"r":1,"a":"g","b":{"p":"/orders","q":{"i":"total","l":1,"vf":"r"}}}}`, want: []string{
+					`{"t":"d","d":{"r":1,"b":{"s":"ok","d":{"o2":{"status":"done","total":200}}}}}`,
+				}},
+			},
+		},
+		{
+			name: "transaction from a stale value",
+			steps: []step{
+				{send: `{"t":"d","d":{"r":1,"a":"p","b":{"p":"/orders/o1/status","d":"done","h":"stale"}}}`, want: []string{
+					`{"t":"d","d":{"r":1,"b":{"s":"datastale","d":"*"}}}`,
+				}},
+				{send: `{"t":"d","d":{"r":2,"a":"p","b":{"p":"/orders/o1/status","d":"done","h":"` + nodeHash("pending") + `"}}}`, want: []string{
+					`{"t":"d","d":{"r":2,"b":{"s":"ok","d":""}}}`,
+				}},
+			},
+		},
+		{
+			name: "credentials are accepted",
+			steps: []step{
+				{send: `{"t":"d","d":{"r":1,"a":"auth","b":{"cred":"token"}}}`, want: []string{
+					`{"t":"d","d":{"r":1,"b":{"s":"ok","d":{}}}}`,
+				}},
+			},
+		},
+		{
+			name: "invalid path",
+			steps: []step{
+				{send: `{"t":"d","d":{"r":1,"a":"p","b":{"p":"/orders/a#b","d":1}}}`, want: []string{
+					`{"t":"d","d":{"r":1,"b":{"s":"invalid_path","d":"*"}}}`,
+				}},
+			},
+		},
+		{
+			name: "unknown action",
+			steps: []step{
+				{send: `{"t":"d","d":{"r":1,"a":"x","b":{}}}`, want: []string{
+					`{"t":"d","d":{"r":1,"b":{"s":"invalid_request","d":"*"}}}`,
+				}},
+			},
+		},
+		{
+			name: "ping",
+			steps: []step{
+				{send: `{"t":"c","d":{"t":"p","d":{}}}`, want: []string{`{"t":"c","d":{"t":"o","d":{}}}`}},
+			},
+		},
+		{
+			name: "keep-alive and a message in several frames",
+			steps: []step{
+				{send: `0`},
+				{send: `2`},
+				{send: `{"t":"d","d":{"r":1,"a":"g",`},
+				{send: `"b":{"p":"/settings/currency"}}}`, want: []string{
+					`{"t":"d","d":{"r":1,"b":{"s":"ok","d":"VND"}}}`,
+				}},
+			},
+		},
+		{
+			name: "message in WebSocket fragments",
+			steps: []step{
+				{send: `{"t":"d","d":{"r":1,"a":"g","b":{"p":"/settings/currency"}}}`, fragments: 3, want: []string{
+					`{"t":"d","d":{"r":1,"b":{"s":"ok","d":"VND"}}}`,
+				}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := newDatabase(seedTree())
+			if err != nil {
+				t.Fatalf("newDatabase: %v", err)
+			}
+			server := httptest.NewServer(db)
+			defer server.Close()
+			c := dialWire(t, server)
+			handshake := `{"t":"c","d":{"t":"h","d":{"ts":"*","v":"5","h":"*","s":"*"}}}`
+			if got := c.message(); !matchJSON(got, decodeJSON(t, handshake)) {
+				t.Fatalf("handshake = %v", got)
+			}
+
+			for _, step := range test.steps {
+				c.send(step.send, step.fragments)
+				for _, want := range step.want {
+					got := c.message()
+					if want == "*" {
+						continue
+					}
+					if !matchJSON(got, decodeJSON(t, want)) {
+						raw, _ := json.Marshal(got)
+						t.Fatalf("after %s\ngot  %s\nwant %s", step.send, raw, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWireOnDisconnect(t *testing.T) {
+	db, err := newDatabase(seedTree())
+	if err != nil {
+		t.Fatalf("newDatabase: %v", err)
+	}
+	server := httptest.NewServer(db)
+	defer server.Close()
+
+	c := dialWire(t, server)
+	c.message()
+	for i, request := range []string{
+		`{"t":"d","d":{"r":1,"a":"o","b":{"p":"/presence/u1","d":null}}}`,
+		`{"t":"d","d":{"r":2,"a":"om","b":{"p":"/orders","d":{"o1/status":"abandoned"}}}}`,
+		`{"t":"d","d":{"r":3,"a":"o","b":{"p":"/settings/currency","d":"USD"}}}`,
+		`{"t":"d","d":{"r":4,"a":"oc","b":{"p":"/settings"}}}`,
+		`{"t":"d","d":{"r":5,"a":"p","b":{"p":"/presence/u1","d":true}}}`,
+	} {
+		c.send(request, 1)
+		if got := c.message(); !matchJSON(got, map[string]any{"t": "d", "d": map[string]any{"r": float64(i + 1), "b": "*"}}) {
+			t.Fatalf("answer to %s = %v", request, got)
+		}
+	}
+
+	// A close handshake, answered with the same status code.
+	c.sendFrame(true, opClose, []byte{0x03, 0xe8})
+	if opcode, payload := c.next(); opcode != opClose || string(payload) != "\x03\xe8" {
+		t.Fatalf("close answered with %#x %q", opcode, payload)
+	}
+
+	want := map[string]any{
+		"orders/o1/status":  "abandoned",
+		"presence/u1":       nil,
+		"settings/currency": "VND",
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		db.mu.RLock()
+		done := true
+		for path, value := range want {
+			if getAt(db.root, splitPath(path)) != value {
+				done = false
+			}
+		}
+		listeners := len(db.listeners)
+		db.mu.RUnlock()
+		if done && listeners == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("onDisconnect writes not applied: %v", db.read(nil, nil, false))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebSocketFrames(t *testing.T) {
+	db, err := newDatabase(seedTree())
+	if err != nil {
+		t.Fatalf("newDatabase: %v", err)
+	}
+	server := httptest.NewServer(db)
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/.ws")
+	if err != nil {
+		t.Fatalf("GET /.ws: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("plain GET /.ws status = %d, want 400", response.StatusCode)
+	}
+
+	c := dialWire(t, server)
+	c.message()
+	c.sendFrame(true, opPing, []byte("hi"))
+	if opcode, payload := c.next(); opcode != opPong || string(payload) != "hi" {
+		t.Errorf("ping answered with %#x %q, want a pong", opcode, payload)
+	}
+	// A long value comes back in a frame with a 64-bit length.
+	long := strings.Repeat("x", 70000)
+	c.send(`{"t":"d","d":{"r":1,"a":"p","b":{"p":"/note","d":"`+long+`"}}}`, 1)
+	c.message()
+	c.send(`{"t":"d","d":{"r":2,"a":"g","b":{"p":"/note"}}}`, 1)
+	if got := c.message(); getAt(got, []string{"d", "b", "d"}) != long {
+		t.Errorf("long value did not come back whole")
+	}
+}
+
+func TestNodeHash(t *testing.T) {
+	// Worked out independently from the client SDKs' hashing: SHA-1 over
+	// "type:value", numbers as the hex of their IEEE 754 bits.
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{nil, ""},
+		{map[string]any{}, ""},
+		{float64(1), "YPVfR2bXt/lcDjiQZ8pOkAd3qkQ="},
+		{-2.5, "eBj7htp2uFU6m+ykmQDcicyETbY="},
+		{"Khách", "QWihX4hCB/kDZWxGfvKa4MgB5Y8="},
+		{true, "E5z61QM0lN/U2WsOnusszCTkR8M="},
+		// Children in key order, integer keys first, empty ones left out.
+		{map[string]any{"b": float64(1), "a": "x", "10": true, "2": map[string]any{}}, "XbnMf9epegI2JWYLkJIJkwpdYM4="},
+	}
+	for _, test := range tests {
+		if got := nodeHash(test.value); got != test.want {
+			t.Errorf("nodeHash(%v) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestDoubleHex(t *testing.T) {
+	// The client SDKs' doubleToIEEE754String, run in Node: it matches the
+	// bits except where its logarithm lands on the wrong exponent.
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{0, "0000000000000000"},
+		{1, "3ff0000000000000"},
+		{-2.5, "c004000000000000"},
+		{0.1, "3fb999999999999a"},
+		{1747200000000, "42796cd3c4000000"},
+		{5e-324, "0000000000000001"},
+		{4.656612873077393e-10, "3df0000000000000"},
+		{1e-300, "01a0000000000000"},
+	}
+	for _, test := range tests {
+		if got := doubleHex(test.value); got != test.want {
+			t.Errorf("doubleHex(%v) = %s, want %s", test.value, got, test.want)
+		}
+	}
+}